@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/turbonomic/turbo-api/pkg/api"
@@ -47,7 +49,7 @@ func addTarget() {
 			},
 		},
 	}
-	if err = turboClient.AddTarget(target, client.API); err != nil {
+	if _, err = turboClient.AddTarget(target, client.API); err != nil {
 		glog.Errorf("Error adding target: %s", err)
 		return
 	}
@@ -95,7 +97,7 @@ func addExternalTarget() {
 	}
 
 	// Make API calls.
-	if err = turboClient.AddTarget(target, client.API); err != nil {
+	if _, err = turboClient.AddTarget(target, client.API); err != nil {
 		fmt.Printf("Error adding target: %s\n", err)
 		return
 	}
@@ -114,10 +116,23 @@ func discoverTargetExample() {
 		glog.Errorf("Error creating client: %s", err)
 	}
 	uuid := "<TARGET_UUID>"
-	resp, err := turboClient.DiscoverTarget(uuid, client.API)
+	status, err := turboClient.DiscoverTarget(uuid, client.API)
 	if err != nil {
-		glog.Errorf("Error adding target: %s", err)
+		glog.Errorf("Error discovering target: %s", err)
+		return
+	}
+	glog.Infof("Discovery status is %+v", status)
+
+	// Wait for the discovery to complete
+	status, err = turboClient.WaitForDiscovery(context.Background(), uuid, client.API,
+		&client.DiscoveryWaitOptions{PollInterval: 10 * time.Second, Timeout: 5 * time.Minute, Since: status})
+	if err != nil {
+		glog.Errorf("Error waiting for target discovery: %s", err)
+		return
+	}
+	if err := status.Err(); err != nil {
+		glog.Errorf("Target discovery failed: %s", err)
 		return
 	}
-	glog.Infof("Response is %+v", resp)
+	glog.Infof("Target discovered successfully at %s", status.LastValidated)
 }
@@ -0,0 +1,57 @@
+package api
+
+// States of an action
+const (
+	ActionStateReady      = "READY"
+	ActionStateCleared    = "CLEARED"
+	ActionStateRejected   = "REJECTED"
+	ActionStateAccepted   = "ACCEPTED"
+	ActionStateQueued     = "QUEUED"
+	ActionStateInProgress = "IN_PROGRESS"
+	ActionStateSucceeded  = "SUCCEEDED"
+	ActionStateFailed     = "FAILED"
+)
+
+// Action defines the protocols of the actions of the api service
+type Action struct {
+	UUID     string `json:"uuid,omitempty"`
+	ActionID int64  `json:"actionID,omitempty"`
+	// Type of the action, i.e. MOVE, RESIZE, PROVISION, SUSPEND and so on.
+	ActionType string `json:"actionType,omitempty"`
+	// State of the action, i.e. READY, ACCEPTED, IN_PROGRESS, SUCCEEDED, FAILED and so on.
+	ActionState string `json:"actionState,omitempty"`
+	// Automation mode of the action, i.e. RECOMMEND, MANUAL, AUTOMATIC and so on.
+	ActionMode string `json:"actionMode,omitempty"`
+	// Human readable description of the action
+	Details    string  `json:"details,omitempty"`
+	Importance float64 `json:"importance,omitempty"`
+	// Entity the action applies to
+	Target *ServiceEntity `json:"target,omitempty"`
+	// Entity the action moves the target from, if any
+	CurrentEntity *ServiceEntity `json:"currentEntity,omitempty"`
+	// Entity the action moves the target to, if any
+	NewEntity    *ServiceEntity `json:"newEntity,omitempty"`
+	CurrentValue string         `json:"currentValue,omitempty"`
+	NewValue     string         `json:"newValue,omitempty"`
+	Risk         *ActionRisk    `json:"risk,omitempty"`
+	CreateTime   string         `json:"createTime,omitempty"`
+	UpdateTime   string         `json:"updateTime,omitempty"`
+	// Progress of the execution of the action, once accepted
+	ExecutionStatus *ActionExecutionStatus `json:"executionStatus,omitempty"`
+}
+
+// ActionExecutionStatus is the progress of the execution of an action
+type ActionExecutionStatus struct {
+	ProgressPercentage int `json:"progressPercentage,omitempty"`
+	// Messages reported while executing the action, which hold the failure reasons when the action failed
+	Messages []string `json:"messages,omitempty"`
+}
+
+// ActionRisk describes the reason of an action
+type ActionRisk struct {
+	Description string `json:"description,omitempty"`
+	// Category of the risk, i.e. Performance Assurance, Efficiency Improvement and so on.
+	SubCategory string  `json:"subCategory,omitempty"`
+	Severity    string  `json:"severity,omitempty"`
+	Importance  float64 `json:"importance,omitempty"`
+}
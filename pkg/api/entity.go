@@ -0,0 +1,26 @@
+package api
+
+// BaseDTO identifies an object of the api service, such as an entity, a group or a template
+type BaseDTO struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// Class of the object, i.e. VirtualMachine, PhysicalMachine, Group and so on.
+	ClassName string `json:"className,omitempty"`
+}
+
+// ServiceEntity defines the protocols of the entities of the api service
+type ServiceEntity struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// Entity type, i.e. VirtualMachine, PhysicalMachine and so on.
+	ClassName string `json:"className,omitempty"`
+	// Environment of the entity, i.e. CLOUD or ONPREM
+	EnvironmentType string `json:"environmentType,omitempty"`
+	// State of the entity, i.e. ACTIVE, IDLE, SUSPEND and so on.
+	State    string `json:"state,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	// Tags of the entity, mapping tag keys to tag values
+	Tags map[string][]string `json:"tags,omitempty"`
+	// Target which discovered the entity
+	DiscoveredBy *BaseDTO `json:"discoveredBy,omitempty"`
+}
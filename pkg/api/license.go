@@ -0,0 +1,32 @@
+package api
+
+// LicenseSummary defines the protocols of the GET /licenses/summary api service.
+// It aggregates all the licenses installed on the server.
+type LicenseSummary struct {
+	// Expiration date of the license, in ISO 8601 format
+	ExpirationDate string `json:"expirationDate,omitempty"`
+	IsExpired      bool   `json:"isExpired"`
+	// Licensed features, i.e. container_control, public_cloud, storage
+	Features []string `json:"features,omitempty"`
+	// Type of the workloads counted against the limit, i.e. VM or SOCKET
+	CountedEntity       string `json:"countedEntity,omitempty"`
+	NumLicensedEntities int    `json:"numLicensedEntities"`
+	NumInUseEntities    int    `json:"numInUseEntities"`
+	IsOverLimit         bool   `json:"isOverLimit"`
+}
+
+// License defines the protocols of the licenses of the api service
+type License struct {
+	UUID                string   `json:"uuid,omitempty"`
+	Filename            string   `json:"filename,omitempty"`
+	Owner               string   `json:"owner,omitempty"`
+	Email               string   `json:"email,omitempty"`
+	Edition             string   `json:"edition,omitempty"`
+	ExpirationDate      string   `json:"expirationDate,omitempty"`
+	CountedEntity       string   `json:"countedEntity,omitempty"`
+	NumLicensedEntities int      `json:"numLicensedEntities,omitempty"`
+	Features            []string `json:"features,omitempty"`
+	IsValid             bool     `json:"isValid"`
+	// Reasons the license is invalid, i.e. EXPIRED, INVALID_CONTENT_TYPE, DUPLICATE_LICENSE
+	ErrorReasons []string `json:"errorReasons,omitempty"`
+}
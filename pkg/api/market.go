@@ -0,0 +1,21 @@
+package api
+
+// RealtimeMarketUUID is the UUID of the market reflecting the current environment, which plans are started from
+const RealtimeMarketUUID = "Market"
+
+// Market defines the protocols of the markets of the api service.
+// The real-time market reflects the current environment, and each plan runs in its own market.
+type Market struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	ClassName   string `json:"className,omitempty"`
+	// State of the market, i.e. RUNNING, SUCCEEDED, FAILED and so on.
+	State string `json:"state,omitempty"`
+	// Progress of the plan in percents
+	StateProgress int `json:"stateProgress,omitempty"`
+	// Scenario the plan market runs
+	Scenario        *Scenario `json:"scenario,omitempty"`
+	EnvironmentType string    `json:"environmentType,omitempty"`
+	RunDate         string    `json:"runDate,omitempty"`
+	RunCompleteDate string    `json:"runCompleteDate,omitempty"`
+}
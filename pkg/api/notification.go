@@ -0,0 +1,43 @@
+package api
+
+// Types of target notifications
+const (
+	TargetNotificationValidation = "VALIDATION"
+	TargetNotificationDiscovery  = "DISCOVERY"
+)
+
+// Notification defines the protocols of the notifications pushed by the api service over WebSocket.
+// Each notification holds one of the notification types, encoded with protobuf in binary messages or with JSON
+// in text messages.
+type Notification struct {
+	BroadcastID string              `json:"broadcastId,omitempty"`
+	Action      *ActionNotification `json:"actionNotification,omitempty"`
+	Market      *MarketNotification `json:"marketNotification,omitempty"`
+	Target      *TargetNotification `json:"targetNotification,omitempty"`
+}
+
+// ActionNotification notifies the progress of the execution of an action
+type ActionNotification struct {
+	ActionID string `json:"actionId"`
+	// State of the action, i.e. IN_PROGRESS, SUCCEEDED, FAILED
+	State              string `json:"state,omitempty"`
+	ProgressPercentage int    `json:"progressPercentage,omitempty"`
+	Description        string `json:"description,omitempty"`
+}
+
+// MarketNotification notifies the progress of a plan market
+type MarketNotification struct {
+	MarketID string `json:"marketId"`
+	// State of the plan, i.e. RUNNING, SUCCEEDED, FAILED
+	State              string `json:"state,omitempty"`
+	ProgressPercentage int    `json:"progressPercentage,omitempty"`
+}
+
+// TargetNotification notifies the result of the validation or discovery of a target
+type TargetNotification struct {
+	TargetID string `json:"targetId"`
+	// Type of the notification, VALIDATION or DISCOVERY
+	Type string `json:"type,omitempty"`
+	// Status description of the target, which holds the error details when the validation or discovery failed
+	Status string `json:"status,omitempty"`
+}
@@ -0,0 +1,59 @@
+package api
+
+// States of a plan market
+const (
+	PlanStateSucceeded = "SUCCEEDED"
+	PlanStateFailed    = "FAILED"
+	PlanStateStopped   = "STOPPED"
+)
+
+// Types of plan scenarios
+const (
+	ScenarioTypeAddWorkload      = "ADD_WORKLOAD"
+	ScenarioTypeDecommissionHost = "DECOMMISSION_HOST"
+	ScenarioTypeCloudMigration   = "CLOUD_MIGRATION"
+	ScenarioTypeCustom           = "CUSTOM"
+)
+
+// Scenario defines the protocols of the POST /scenarios api service.
+// A scenario describes the changes applied to the topology in a what-if plan.
+type Scenario struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName"`
+	// Type of the scenario, i.e. ADD_WORKLOAD, DECOMMISSION_HOST, CLOUD_MIGRATION and so on.
+	Type string `json:"type,omitempty"`
+	// Entities or groups the plan is scoped to
+	Scope []*BaseDTO `json:"scope,omitempty"`
+	// Days in the future the plan is projected to
+	ProjectionDays  []int            `json:"projectionDays,omitempty"`
+	TopologyChanges *TopologyChanges `json:"topologyChanges,omitempty"`
+}
+
+// TopologyChanges lists the entities added, removed or migrated in a scenario
+type TopologyChanges struct {
+	AddList     []*AddObject     `json:"addList,omitempty"`
+	RemoveList  []*RemoveObject  `json:"removeList,omitempty"`
+	MigrateList []*MigrateObject `json:"migrateList,omitempty"`
+}
+
+// AddObject adds copies of an entity, a group or a template to a scenario
+type AddObject struct {
+	Target         *BaseDTO `json:"target"`
+	Count          int      `json:"count"`
+	ProjectionDays []int    `json:"projectionDays,omitempty"`
+}
+
+// RemoveObject removes an entity or a group from a scenario
+type RemoveObject struct {
+	Target        *BaseDTO `json:"target"`
+	ProjectionDay int      `json:"projectionDay"`
+}
+
+// MigrateObject migrates an entity or a group to a destination, i.e. a cloud region, in a scenario
+type MigrateObject struct {
+	Source                      *BaseDTO `json:"source"`
+	Destination                 *BaseDTO `json:"destination"`
+	ProjectionDay               int      `json:"projectionDay"`
+	DestinationEntityType       string   `json:"destinationEntityType,omitempty"`
+	RemoveNonMigratingWorkloads bool     `json:"removeNonMigratingWorkloads,omitempty"`
+}
@@ -10,4 +10,17 @@ const (
 	Resource_Type_External_Target ResourceType = "externaltargets"
 	Resource_Type_hydra_token     ResourceType = "token"
 	Resource_Type_auth_token      ResourceType = "exchange"
+	Resource_Type_Scenarios       ResourceType = "scenarios"
+	Resource_Type_Markets         ResourceType = "markets"
+	Resource_Type_Templates       ResourceType = "templates"
+	Resource_Type_Stats           ResourceType = "stats"
+	Resource_Type_Search          ResourceType = "search"
+	Resource_Type_Users           ResourceType = "users"
+	Resource_Type_Licenses        ResourceType = "licenses"
+	Resource_Type_Tags            ResourceType = "tags"
+	Resource_Type_Entities        ResourceType = "entities"
+	Resource_Type_Groups          ResourceType = "groups"
+	Resource_Type_Actions         ResourceType = "actions"
+	Resource_Type_Workflows       ResourceType = "workflows"
+	Resource_Type_Admin           ResourceType = "admin"
 )
@@ -0,0 +1,49 @@
+package api
+
+// EntityType is the type of the entities of the api service, i.e. VirtualMachine
+type EntityType string
+
+// Types of entities
+const (
+	EntityTypeVirtualMachine  EntityType = "VirtualMachine"
+	EntityTypePhysicalMachine EntityType = "PhysicalMachine"
+	EntityTypeStorage         EntityType = "Storage"
+	EntityTypeDataCenter      EntityType = "DataCenter"
+	EntityTypeContainer       EntityType = "Container"
+	EntityTypeContainerPod    EntityType = "ContainerPod"
+)
+
+// Environments of entities
+const (
+	EnvironmentTypeCloud  = "CLOUD"
+	EnvironmentTypeOnPrem = "ONPREM"
+	EnvironmentTypeHybrid = "HYBRID"
+)
+
+// Expression types of filter criteria
+const (
+	FilterExpEquals        = "EQ"
+	FilterExpNotEquals     = "NEQ"
+	FilterExpRegexMatch    = "RXEQ"
+	FilterExpRegexNotMatch = "RXNEQ"
+)
+
+// SearchRequest defines the protocols of the body of the POST /search api service
+type SearchRequest struct {
+	// Type of the searched entities
+	ClassName string `json:"className"`
+	// How the criteria are combined, AND or OR
+	LogicalOperator string            `json:"logicalOperator,omitempty"`
+	CriteriaList    []*FilterCriteria `json:"criteriaList"`
+	// UUIDs of the groups, or other scopes, the searched entities are members of
+	Scope           []string `json:"scope,omitempty"`
+	EnvironmentType string   `json:"environmentType,omitempty"`
+}
+
+// FilterCriteria is a criterion of a search, i.e. {"filterType":"vmsByName","expType":"RXEQ","expVal":"^web.*"}
+type FilterCriteria struct {
+	FilterType    string `json:"filterType"`
+	ExpType       string `json:"expType"`
+	ExpVal        string `json:"expVal"`
+	CaseSensitive bool   `json:"caseSensitive"`
+}
@@ -0,0 +1,56 @@
+package api
+
+// StatSnapshot defines the protocols of the statistics of the api service at a point in time
+type StatSnapshot struct {
+	// Date of the snapshot, in ISO 8601 format
+	Date string `json:"date,omitempty"`
+	// Whether the snapshot is historical, current or projected
+	Epoch      string  `json:"epoch,omitempty"`
+	Statistics []*Stat `json:"statistics,omitempty"`
+}
+
+// Stat is the value of a statistic, i.e. VCPU, VMem, costPrice and so on.
+type Stat struct {
+	Name     string      `json:"name"`
+	Units    string      `json:"units,omitempty"`
+	Value    float64     `json:"value,omitempty"`
+	Values   *StatValues `json:"values,omitempty"`
+	Capacity *StatValues `json:"capacity,omitempty"`
+	// Type of the entities related to the statistic, if any
+	RelatedEntityType string `json:"relatedEntityType,omitempty"`
+	// Filters identifying the group the statistic is aggregated over when grouped, i.e. by key or by provider
+	Filters []*StatFilter `json:"filters,omitempty"`
+}
+
+// StatFilter restricts or identifies the values of a statistic, i.e. {"type":"key","value":"vol-1"}
+type StatFilter struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// StatValues aggregates the values of a statistic over a period
+type StatValues struct {
+	Avg   float64 `json:"avg"`
+	Max   float64 `json:"max"`
+	Min   float64 `json:"min"`
+	Total float64 `json:"total"`
+}
+
+// StatPeriodInput defines the protocols of a statistics query of the api service
+type StatPeriodInput struct {
+	// Start and end dates of the period, in milliseconds since epoch, ISO 8601 format
+	// or relative to the current time, i.e. -1d, -12h, +7d
+	StartDate  string       `json:"startDate,omitempty"`
+	EndDate    string       `json:"endDate,omitempty"`
+	Statistics []*StatInput `json:"statistics,omitempty"`
+}
+
+// StatInput requests a statistic in a statistics query
+type StatInput struct {
+	Name string `json:"name"`
+	// Type of the related entities the statistic is aggregated from, i.e. VirtualMachine
+	RelatedEntityType string `json:"relatedEntityType,omitempty"`
+	// Criteria to group the statistic by, i.e. key, virtualDisk, businessUnit
+	GroupBy []string      `json:"groupBy,omitempty"`
+	Filters []*StatFilter `json:"filters,omitempty"`
+}
@@ -0,0 +1,8 @@
+package api
+
+// Tag defines the protocols of the tags of the api service.
+// A tag maps a key to one or more values, i.e. env: [prod, staging].
+type Tag struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
@@ -0,0 +1,35 @@
+package api
+
+// Classes of the entities templates describe
+const (
+	TemplateClassVirtualMachine  = "VirtualMachine"
+	TemplateClassPhysicalMachine = "PhysicalMachine"
+	TemplateClassStorage         = "Storage"
+	TemplateClassContainer       = "Container"
+)
+
+// Template defines the protocols of the templates of the api service.
+// A template describes the capacity of an entity added by a plan or reserved by a reservation.
+type Template struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName"`
+	// Class of the template; the class of the described entity, i.e. VirtualMachine, when creating a template,
+	// and the profile class, i.e. VirtualMachineProfile, when read from the server
+	ClassName   string  `json:"className"`
+	Description string  `json:"description,omitempty"`
+	Model       string  `json:"model,omitempty"`
+	Vendor      string  `json:"vendor,omitempty"`
+	Price       float64 `json:"price,omitempty"`
+	// Whether the template is discovered from a target, in which case it cannot be modified
+	Discovered bool `json:"discovered,omitempty"`
+
+	// Resource specifications, i.e. numOfCpu, cpuSpeed, memorySize, diskSize and so on.
+	ComputeResources        []*TemplateResource `json:"computeResources,omitempty"`
+	StorageResources        []*TemplateResource `json:"storageResources,omitempty"`
+	InfrastructureResources []*TemplateResource `json:"infrastructureResources,omitempty"`
+}
+
+// TemplateResource is a set of resource specifications of a template
+type TemplateResource struct {
+	Stats []*Stat `json:"stats"`
+}
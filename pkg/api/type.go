@@ -32,6 +32,10 @@ type TargetInfo struct {
 	TargetID    int64       `json:"targetId,string"`
 	DisplayName string      `json:"displayName"`
 	TargetSpec  *TargetSpec `json:"spec"`
+	// Description of the validation or discovery status of the target
+	Status string `json:"status,omitempty"`
+	// Time of the last validation or discovery of the target
+	LastValidationTime string `json:"lastValidationTime,omitempty"`
 }
 
 // TargetSpec defines the protocols of the POST /target topology processor service
@@ -55,6 +59,26 @@ type ProbeDescription struct {
 	ID       int64  `json:"id,string"`
 	Category string `json:"category"`
 	Type     string `json:"type"`
+	// List of field names, identifying the targets of this probe.
+	IdentifyingFields []string `json:"identifyingFields,omitempty"`
+}
+
+// ProbeRegistration defines the protocols of the GET /probe/registration topology-processor service.
+// Each registration represents a probe instance currently connected to the topology processor.
+type ProbeRegistration struct {
+	ID      int64 `json:"id,string"`
+	ProbeID int64 `json:"probeId,string"`
+	// The communication binding channel the probe instance registered with
+	CommunicationBindingChannel string `json:"communicationBindingChannel,omitempty"`
+	// Version of the SDK the probe instance is built with
+	Version string `json:"version,omitempty"`
+	// Registration time in milliseconds since epoch
+	RegisteredTime int64  `json:"registeredTime,omitempty"`
+	DisplayName    string `json:"displayName,omitempty"`
+	// Health of the connection to the probe instance, i.e. NORMAL, MINOR, MAJOR or CRITICAL
+	HealthState string `json:"healthState,omitempty"`
+	// Description of the connection status of the probe instance
+	Status string `json:"status,omitempty"`
 }
 
 type InputField struct {
@@ -0,0 +1,59 @@
+package api
+
+// Roles of users
+const (
+	RoleAdministrator  = "ADMINISTRATOR"
+	RoleSiteAdmin      = "SITE_ADMIN"
+	RoleAutomator      = "AUTOMATOR"
+	RoleDeployer       = "DEPLOYER"
+	RoleAdvisor        = "ADVISOR"
+	RoleObserver       = "OBSERVER"
+	RoleSharedAdvisor  = "SHARED_ADVISOR"
+	RoleSharedObserver = "SHARED_OBSERVER"
+)
+
+// Providers authenticating users
+const (
+	LoginProviderLocal = "Local"
+	LoginProviderLDAP  = "LDAP"
+)
+
+// Types of users; the scope of shared users is restricted to the entities of their scope
+const (
+	UserTypeDedicated = "DedicatedCustomer"
+	UserTypeShared    = "SharedCustomer"
+)
+
+// User defines the protocols of the users of the api service
+type User struct {
+	UUID     string `json:"uuid,omitempty"`
+	Username string `json:"username"`
+	// Password of a local user, only sent when creating or updating the user
+	Password    string `json:"password,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// Provider authenticating the user, Local or LDAP
+	LoginProvider string `json:"loginProvider,omitempty"`
+	// Type of the user, DedicatedCustomer or SharedCustomer
+	Type     string  `json:"type,omitempty"`
+	RoleName string  `json:"roleName,omitempty"`
+	Roles    []*Role `json:"roles,omitempty"`
+	// Groups the user is restricted to, required for shared users
+	Scope []*BaseDTO `json:"scope,omitempty"`
+}
+
+// Role is a role assigned to a user or an AD group, i.e. ADMINISTRATOR
+type Role struct {
+	Name string `json:"name"`
+}
+
+// ADGroup defines the protocols of the mappings of LDAP/AD groups to roles of the api service.
+// The members of the AD group log in with the role and scope of the mapping.
+type ADGroup struct {
+	UUID string `json:"uuid,omitempty"`
+	// Name of the AD group, i.e. turbo-admins
+	DisplayName string `json:"displayName"`
+	// Type of the members of the group, DedicatedCustomer or SharedCustomer
+	Type     string     `json:"type,omitempty"`
+	RoleName string     `json:"roleName"`
+	Scope    []*BaseDTO `json:"scope,omitempty"`
+}
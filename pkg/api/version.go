@@ -0,0 +1,23 @@
+package api
+
+// Market versions reported by the server
+const (
+	// Market version of classic servers, which expose the api service only
+	MarketVersionClassic = 1
+	// Market version of XL servers, which are split into services like topology processor
+	MarketVersionXL = 2
+)
+
+// ProductVersion defines the protocols of the GET /admin/versions api service
+type ProductVersion struct {
+	// Description of the product and its build,
+	// i.e. Turbonomic Operations Manager 8.4.2 (Build "20211021092220000") "2021-10-22 12:39:53"
+	VersionInfo string `json:"versionInfo,omitempty"`
+	// Version of the product, i.e. 8.4.2, reported by recent servers only
+	Version string `json:"version,omitempty"`
+	// Build of the product, reported by recent servers only
+	Build string `json:"build,omitempty"`
+	// Available updates, if any
+	Updates       string `json:"updates,omitempty"`
+	MarketVersion int    `json:"marketVersion,omitempty"`
+}
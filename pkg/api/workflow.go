@@ -0,0 +1,44 @@
+package api
+
+// Types of workflows
+const (
+	WorkflowTypeActionScript = "ACTION_SCRIPT"
+	WorkflowTypeWebhook      = "WEBHOOK"
+	WorkflowTypeAnsible      = "ANSIBLE"
+	WorkflowTypeServiceNow   = "SERVICENOW"
+)
+
+// Workflow defines the protocols of the orchestration workflows of the api service.
+// Workflows run in place of, or before and after, the execution of actions; they are discovered
+// from orchestrator targets, i.e. action scripts and Ansible, or created, i.e. webhooks.
+type Workflow struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"displayName"`
+	ClassName   string `json:"className,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type of the workflow, i.e. ACTION_SCRIPT, WEBHOOK, ANSIBLE and so on.
+	Type string `json:"type,omitempty"`
+	// Target which discovered the workflow, if any
+	DiscoveredBy *BaseDTO             `json:"discoveredBy,omitempty"`
+	Parameters   []*WorkflowParameter `json:"parameters,omitempty"`
+	// Details of webhook workflows
+	TypeSpecificDetails *WebhookDetails `json:"typeSpecificDetails,omitempty"`
+}
+
+// WorkflowParameter is a parameter passed to a workflow
+type WorkflowParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Mandatory   bool   `json:"mandatory,omitempty"`
+}
+
+// WebhookDetails describes the request sent by a webhook workflow
+type WebhookDetails struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	// HTTP method of the request, i.e. POST
+	Method string `json:"method"`
+	// Template of the request body, which the action details are substituted into
+	Template string `json:"template,omitempty"`
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+var (
+	defaultActionPollInterval = 5 * time.Second
+	defaultActionTimeout      = 30 * time.Minute
+	defaultActionPollFailures = 5
+)
+
+// ErrActionNotFound is returned when an action does not exist, i.e. when it was removed from the server
+var ErrActionNotFound = errors.New("action not found")
+
+// ActionClient accepts actions via api service and tracks their execution until they complete.
+type ActionClient struct {
+	api *APIClient
+}
+
+// ActionWaitOptions configures how WaitForAction polls the state of an action
+type ActionWaitOptions struct {
+	// Interval between two polls of the action state, defaults to 5 seconds
+	PollInterval time.Duration
+	// Maximum time to wait for the action to complete, defaults to 30 minutes
+	Timeout time.Duration
+	// Number of polls in a row failing to get the action before giving up, defaults to 5
+	MaxPollFailures int
+	// Progress is called with the action after each poll, if set
+	Progress func(action *api.Action)
+}
+
+// ActionExecutionResult is the result of the execution of an action
+type ActionExecutionResult struct {
+	// Action as last read from the server
+	Action *api.Action
+	// Final state of the action, SUCCEEDED, FAILED, or CLEARED or REJECTED if it was not executed
+	State string
+	// Reasons reported by the server when the action failed or was not executed
+	FailureReasons []string
+	// Time from the acceptance to the completion of the action
+	Duration time.Duration
+}
+
+// NewActionClient builds a client executing actions with the session of the given api service client
+func NewActionClient(apiClient *APIClient) *ActionClient {
+	return &ActionClient{api: apiClient}
+}
+
+// Succeeded returns true if the action was executed successfully
+func (r *ActionExecutionResult) Succeeded() bool {
+	return r.State == api.ActionStateSucceeded
+}
+
+// Err returns an error with the failure reasons if the action failed or was not executed
+func (r *ActionExecutionResult) Err() error {
+	if r.Succeeded() {
+		return nil
+	}
+	if r.State == api.ActionStateFailed {
+		return fmt.Errorf("action %s failed: %s", r.Action.UUID, strings.Join(r.FailureReasons, "; "))
+	}
+	return fmt.Errorf("action %s was %s instead of being executed: %s", r.Action.UUID, strings.ToLower(r.State),
+		strings.Join(r.FailureReasons, "; "))
+}
+
+// GetAction gets the action with the given UUID, including its state.
+// It returns an error wrapping ErrActionNotFound if the action does not exist.
+func (c *ActionClient) GetAction(uuid string) (*api.Action, error) {
+	response, err := c.api.doWithSession(func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Actions).Name(uuid).
+			Header("Accept", "application/json")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get action request failed: %w", err)
+	}
+	if response.statusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %v", ErrActionNotFound, buildStatusError("get action", response.statusCode, response.status, response.body))
+	}
+	if response.statusCode != http.StatusOK {
+		return nil, buildStatusError("get action", response.statusCode, response.status, response.body)
+	}
+	var action api.Action
+	if err := json.Unmarshal([]byte(response.body), &action); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall get action response: %v", err)
+	}
+	return &action, nil
+}
+
+// AcceptAction accepts the action with the given UUID, which queues it for execution
+func (c *ActionClient) AcceptAction(uuid string) error {
+	if err := c.api.doJSON("accept action", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Actions).Name(uuid).Param("accept", "true")
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully accepted action %v.", uuid)
+	return nil
+}
+
+// ExecuteAction accepts the action with the given UUID and blocks until its execution completes.
+// A failed execution is reported in the returned result, see ActionExecutionResult.Err.
+func (c *ActionClient) ExecuteAction(ctx context.Context, uuid string) (*ActionExecutionResult, error) {
+	return c.ExecuteActionWithOptions(ctx, uuid, nil)
+}
+
+// ExecuteActionWithOptions is ExecuteAction polling the state of the action as configured by opts
+func (c *ActionClient) ExecuteActionWithOptions(ctx context.Context, uuid string,
+	opts *ActionWaitOptions) (*ActionExecutionResult, error) {
+	start := time.Now()
+	if err := c.AcceptAction(uuid); err != nil {
+		return nil, err
+	}
+	result, err := c.WaitForAction(ctx, uuid, opts)
+	if result != nil {
+		result.Duration = time.Since(start)
+	}
+	return result, err
+}
+
+// WaitForAction polls the state of an accepted action until it succeeds or fails, or is cleared or rejected
+// instead of being executed, which is reported in the returned result. It returns an error if the context is done,
+// the timeout in opts expires, the action does not exist anymore, or the action cannot be got in too many polls
+// in a row.
+func (c *ActionClient) WaitForAction(ctx context.Context, uuid string,
+	opts *ActionWaitOptions) (*ActionExecutionResult, error) {
+	pollInterval, timeout, maxPollFailures := defaultActionPollInterval, defaultActionTimeout, defaultActionPollFailures
+	var progress func(action *api.Action)
+	if opts != nil {
+		if opts.PollInterval > 0 {
+			pollInterval = opts.PollInterval
+		}
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+		if opts.MaxPollFailures > 0 {
+			maxPollFailures = opts.MaxPollFailures
+		}
+		progress = opts.Progress
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	pollFailures := 0
+	for {
+		action, err := c.GetAction(uuid)
+		switch {
+		case errors.Is(err, ErrActionNotFound):
+			return nil, fmt.Errorf("failed to wait for action %s: %w", uuid, err)
+		case err != nil:
+			pollFailures++
+			if pollFailures >= maxPollFailures {
+				return nil, fmt.Errorf("failed to get state of action %s %d times in a row: %w",
+					uuid, pollFailures, err)
+			}
+			glog.Warningf("Failed to get state of action %s: %v", uuid, err)
+		default:
+			pollFailures = 0
+			glog.V(4).Infof("Action %s is %s", uuid, action.ActionState)
+			if progress != nil {
+				progress(action)
+			}
+			switch action.ActionState {
+			case api.ActionStateSucceeded:
+				return &ActionExecutionResult{Action: action, State: action.ActionState}, nil
+			case api.ActionStateFailed, api.ActionStateCleared, api.ActionStateRejected:
+				return &ActionExecutionResult{Action: action, State: action.ActionState,
+					FailureReasons: actionFailureReasons(action)}, nil
+			}
+		}
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("stopped waiting for action %s: %w", uuid, ctx.Err())
+			}
+			return nil, fmt.Errorf("timed out after %v waiting for action %s", timeout, uuid)
+		case <-ticker.C:
+		}
+	}
+}
+
+// actionFailureReasons returns the reasons of the failure of an action, or of its clearing or rejection,
+// falling back to the details of the action when the execution reported no message
+func actionFailureReasons(action *api.Action) []string {
+	if action.ExecutionStatus != nil && len(action.ExecutionStatus.Messages) > 0 {
+		return action.ExecutionStatus.Messages
+	}
+	if action.Details != "" {
+		return []string{action.Details}
+	}
+	return []string{"unknown reason"}
+}
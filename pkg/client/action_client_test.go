@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// newTestActionServer simulates actions executing in three polls and ending in the state given by their UUID
+func newTestActionServer(t *testing.T) (*ActionClient, func()) {
+	var lock sync.Mutex
+	polls := make(map[string]int)
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		uuid := r.URL.Path[len("/actions/"):]
+		switch {
+		case r.Method == "POST" && uuid != "missing":
+			assert.Equal(t, "true", r.URL.Query().Get("accept"))
+			polls[uuid] = 0
+			fmt.Fprintf(w, `{"uuid":%q,"actionState":"ACCEPTED"}`, uuid)
+		case r.Method == "GET" && uuid != "missing":
+			polls[uuid]++
+			if polls[uuid] < 3 {
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"IN_PROGRESS","executionStatus":{"progressPercentage":%d}}`,
+					uuid, polls[uuid]*40)
+				return
+			}
+			switch uuid {
+			case "SUCCEEDED":
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"SUCCEEDED","executionStatus":{"progressPercentage":100}}`, uuid)
+			case "FAILED":
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"FAILED","executionStatus":{"progressPercentage":100,`+
+					`"messages":["host is in maintenance","timed out"]}}`, uuid)
+			case "FAILED_WITHOUT_MESSAGE":
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"FAILED","details":"Move VM from host-1 to host-2"}`, uuid)
+			case "CLEARED":
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"CLEARED","details":"Move VM from host-1 to host-2"}`, uuid)
+			case "REMOVED":
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message":"action not found"}`)
+			case "UNAVAILABLE":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				fmt.Fprintf(w, `{"uuid":%q,"actionState":"IN_PROGRESS"}`, uuid)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"action not found"}`)
+		}
+	}, "actions")
+	defaultActionPollInterval = time.Millisecond
+	return NewActionClient(apiClient), func() {
+		server.Close()
+		defaultActionPollInterval = 5 * time.Second
+	}
+}
+
+func TestActionClient_ExecuteAction(t *testing.T) {
+	actionClient, closeServer := newTestActionServer(t)
+	defer closeServer()
+
+	table := []struct {
+		uuid            string
+		expectedState   string
+		expectedReasons []string
+		expectedErr     string
+	}{
+		{"SUCCEEDED", api.ActionStateSucceeded, nil, ""},
+		{"FAILED", api.ActionStateFailed, []string{"host is in maintenance", "timed out"},
+			"action FAILED failed: host is in maintenance; timed out"},
+		{"FAILED_WITHOUT_MESSAGE", api.ActionStateFailed, []string{"Move VM from host-1 to host-2"},
+			"action FAILED_WITHOUT_MESSAGE failed: Move VM from host-1 to host-2"},
+		{"CLEARED", api.ActionStateCleared, []string{"Move VM from host-1 to host-2"},
+			"action CLEARED was cleared instead of being executed: Move VM from host-1 to host-2"},
+	}
+	for _, item := range table {
+		result, err := actionClient.ExecuteAction(context.Background(), item.uuid)
+		assert.NoError(t, err, item.uuid)
+		assert.Equal(t, item.expectedState, result.State, item.uuid)
+		assert.Equal(t, item.expectedReasons, result.FailureReasons, item.uuid)
+		assert.Equal(t, item.expectedState == api.ActionStateSucceeded, result.Succeeded(), item.uuid)
+		if item.expectedErr == "" {
+			assert.NoError(t, result.Err(), item.uuid)
+		} else {
+			assert.EqualError(t, result.Err(), item.expectedErr, item.uuid)
+		}
+		assert.True(t, result.Duration > 0, item.uuid)
+	}
+
+	_, err := actionClient.ExecuteAction(context.Background(), "missing")
+	assert.EqualError(t, err, "unsuccessful accept action response: 404 Not Found. action not found.")
+}
+
+func TestActionClient_WaitForAction_Errors(t *testing.T) {
+	actionClient, closeServer := newTestActionServer(t)
+	defer closeServer()
+
+	// The action removed from the server is not waited for until the timeout
+	_, err := actionClient.ExecuteAction(context.Background(), "REMOVED")
+	assert.True(t, errors.Is(err, ErrActionNotFound), "%v", err)
+	assert.EqualError(t, err, "failed to wait for action REMOVED: action not found: "+
+		"unsuccessful get action response: 404 Not Found. action not found.")
+
+	_, err = actionClient.ExecuteActionWithOptions(context.Background(), "UNAVAILABLE",
+		&ActionWaitOptions{MaxPollFailures: 2})
+	assert.EqualError(t, err, "failed to get state of action UNAVAILABLE 2 times in a row: "+
+		"unsuccessful get action response: 503 Service Unavailable.")
+
+	_, err = actionClient.WaitForAction(context.Background(), "STUCK", &ActionWaitOptions{Timeout: 20 * time.Millisecond})
+	assert.EqualError(t, err, "timed out after 20ms waiting for action STUCK")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = actionClient.WaitForAction(ctx, "STUCK", nil)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.EqualError(t, err, "stopped waiting for action STUCK: context canceled")
+}
+
+func TestActionClient_ExecuteActionWithOptions(t *testing.T) {
+	actionClient, closeServer := newTestActionServer(t)
+	defer closeServer()
+
+	var progress []int
+	result, err := actionClient.ExecuteActionWithOptions(context.Background(), "SUCCEEDED", &ActionWaitOptions{
+		Progress: func(action *api.Action) {
+			progress = append(progress, action.ExecutionStatus.ProgressPercentage)
+		},
+	})
+	assert.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.Equal(t, []int{40, 80, 100}, progress)
+
+	_, err = actionClient.ExecuteActionWithOptions(context.Background(), "STUCK",
+		&ActionWaitOptions{Timeout: 50 * time.Millisecond})
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// Environment variables describing the action passed by the action script probe to the action scripts it runs
+const (
+	ActionScriptActionUUID  = "VMT_ACTION_INTERNAL"
+	ActionScriptActionName  = "VMT_ACTION_NAME"
+	ActionScriptTargetUUID  = "VMT_TARGET_INTERNAL"
+	ActionScriptTargetName  = "VMT_TARGET_NAME"
+	ActionScriptCurrentUUID = "VMT_CURRENT_INTERNAL"
+	ActionScriptCurrentName = "VMT_CURRENT_NAME"
+	ActionScriptNewUUID     = "VMT_NEW_INTERNAL"
+	ActionScriptNewName     = "VMT_NEW_NAME"
+)
+
+// Exit codes reporting the outcome of an action script to the action script probe
+const (
+	ActionScriptSucceeded = 0
+	ActionScriptFailed    = 1
+)
+
+// ActionScriptCall is the call of an action script by the action script probe, when running an action
+type ActionScriptCall struct {
+	// Action being run, as passed on the standard input of the script, or nil if the probe passed none
+	Action *api.Action
+	// UUID and name of the action
+	ActionUUID string
+	ActionName string
+	// UUIDs and names of the entity the action applies to, and of the entities it moves the entity from and to
+	TargetUUID  string
+	TargetName  string
+	CurrentUUID string
+	CurrentName string
+	NewUUID     string
+	NewName     string
+}
+
+// ActionScriptHandler is called back with the action run by an action script; an error fails the action
+type ActionScriptHandler func(ctx context.Context, call *ActionScriptCall) error
+
+// RunActionScript serves the call of the action script probe to the running program, registered as an action
+// script workflow: it reads the action from the environment and the standard input, calls handler back with it,
+// and returns the exit code reporting the outcome to the probe, i.e. os.Exit(RunActionScript(ctx, handler)).
+func RunActionScript(ctx context.Context, handler ActionScriptHandler) int {
+	return runActionScript(ctx, os.Getenv, os.Stdin, os.Stderr, handler)
+}
+
+func runActionScript(ctx context.Context, getenv func(string) string, input io.Reader, output io.Writer,
+	handler ActionScriptHandler) int {
+	call, err := readActionScriptCall(getenv, input)
+	if err != nil {
+		fmt.Fprintf(output, "Failed to read the action passed to the action script: %v\n", err)
+		return ActionScriptFailed
+	}
+	glog.V(2).Infof("Running action script for action %s: %s", call.ActionUUID, call.ActionName)
+	if err := handler(ctx, call); err != nil {
+		// The output of the script is recorded as the failure reason of the action
+		fmt.Fprintf(output, "Action %s failed: %v\n", call.ActionUUID, err)
+		return ActionScriptFailed
+	}
+	return ActionScriptSucceeded
+}
+
+// readActionScriptCall reads the action described by the environment, and detailed by the JSON input if any
+func readActionScriptCall(getenv func(string) string, input io.Reader) (*ActionScriptCall, error) {
+	call := &ActionScriptCall{
+		ActionUUID:  getenv(ActionScriptActionUUID),
+		ActionName:  getenv(ActionScriptActionName),
+		TargetUUID:  getenv(ActionScriptTargetUUID),
+		TargetName:  getenv(ActionScriptTargetName),
+		CurrentUUID: getenv(ActionScriptCurrentUUID),
+		CurrentName: getenv(ActionScriptCurrentName),
+		NewUUID:     getenv(ActionScriptNewUUID),
+		NewName:     getenv(ActionScriptNewName),
+	}
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(data)) != "" {
+		var action api.Action
+		if err := json.Unmarshal(data, &action); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall action: %v", err)
+		}
+		call.Action = &action
+		if call.ActionUUID == "" {
+			call.ActionUUID = action.UUID
+		}
+	}
+	if call.ActionUUID == "" {
+		return nil, fmt.Errorf("no action passed by the action script probe")
+	}
+	return call, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestRunActionScript(t *testing.T) {
+	env := map[string]string{
+		ActionScriptActionUUID: "100",
+		ActionScriptActionName: "MOVE",
+		ActionScriptTargetUUID: "200",
+		ActionScriptTargetName: "vm-1",
+		ActionScriptNewUUID:    "300",
+		ActionScriptNewName:    "host-2",
+	}
+	table := []struct {
+		env            map[string]string
+		input          string
+		handlerError   error
+		expectedCall   *ActionScriptCall
+		expectedCode   int
+		expectedOutput string
+	}{
+		// Action described by the environment and detailed by the input
+		{
+			env:   env,
+			input: `{"uuid":"100","actionType":"MOVE","actionState":"IN_PROGRESS"}`,
+			expectedCall: &ActionScriptCall{
+				Action:     &api.Action{UUID: "100", ActionType: "MOVE", ActionState: "IN_PROGRESS"},
+				ActionUUID: "100", ActionName: "MOVE", TargetUUID: "200", TargetName: "vm-1",
+				NewUUID: "300", NewName: "host-2",
+			},
+			expectedCode: ActionScriptSucceeded,
+		},
+		// Action described by the environment only
+		{
+			env: env,
+			expectedCall: &ActionScriptCall{
+				ActionUUID: "100", ActionName: "MOVE", TargetUUID: "200", TargetName: "vm-1",
+				NewUUID: "300", NewName: "host-2",
+			},
+			expectedCode: ActionScriptSucceeded,
+		},
+		// Action described by the input only
+		{
+			input: `{"uuid":"100","actionType":"RESIZE"}`,
+			expectedCall: &ActionScriptCall{
+				Action:     &api.Action{UUID: "100", ActionType: "RESIZE"},
+				ActionUUID: "100",
+			},
+			expectedCode: ActionScriptSucceeded,
+		},
+		// Failed action
+		{
+			env:          env,
+			handlerError: errors.New("host-2 is in maintenance"),
+			expectedCall: &ActionScriptCall{
+				ActionUUID: "100", ActionName: "MOVE", TargetUUID: "200", TargetName: "vm-1",
+				NewUUID: "300", NewName: "host-2",
+			},
+			expectedCode:   ActionScriptFailed,
+			expectedOutput: "Action 100 failed: host-2 is in maintenance\n",
+		},
+		// Invalid input
+		{
+			env:          env,
+			input:        `{"uuid":`,
+			expectedCode: ActionScriptFailed,
+			expectedOutput: "Failed to read the action passed to the action script: " +
+				"failed to unmarshall action: unexpected end of JSON input\n",
+		},
+		// No action
+		{
+			expectedCode: ActionScriptFailed,
+			expectedOutput: "Failed to read the action passed to the action script: " +
+				"no action passed by the action script probe\n",
+		},
+	}
+	for _, item := range table {
+		var call *ActionScriptCall
+		var output bytes.Buffer
+		code := runActionScript(context.Background(), func(name string) string { return item.env[name] },
+			strings.NewReader(item.input), &output, func(ctx context.Context, c *ActionScriptCall) error {
+				call = c
+				return item.handlerError
+			})
+		assert.Equal(t, item.expectedCall, call)
+		assert.Equal(t, item.expectedCode, code)
+		assert.Equal(t, item.expectedOutput, output.String())
+	}
+}
@@ -2,28 +2,58 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/turbonomic/turbo-api/pkg/api"
 )
 
-// APIClient connects to api service through ingress
+// APIClient connects to api service through ingress.
+// It is safe for concurrent use by multiple goroutines: concurrent requests share a single
+// login session and a single Hydra access token.
 type APIClient struct {
 	*RESTClient
+	// Cookie of the login session, set when the client logs in, and used if set beforehand.
+	//
+	// Deprecated: reading or setting it while the client is in use is not safe; use GetSessionCookie instead.
 	SessionCookie *http.Cookie
 	ClientId      string
 	ClientSecret  string
+	// Matcher of existing targets with the added ones, MatchByIdentifyingFields if nil
+	TargetMatcher TargetMatcher
+	// Confirmation of the recreation of existing targets of another probe type, allowed if nil
+	RecreateConfirmation RecreateConfirmation
+	// Whether existing targets whose only differing fields are secret fields masked by the server are left untouched
+	// and reported as TargetSecretsUnverified, instead of being updated since their secrets may have changed
+	SkipMaskedSecrets bool
+	// Whether the requests are sent without logging in, for a service which does not need a session
+	Unauthenticated bool
+
+	// sessionLock guards sessionCookie and serializes logins,
+	// so that concurrent requests without a session trigger a single login
+	sessionLock   sync.Mutex
+	sessionCookie *http.Cookie
+
+	// tokenLock guards the cached Hydra access token and serializes its refresh
+	tokenLock        sync.Mutex
+	hydraToken       string
+	hydraTokenExpiry time.Time
 }
 
 const (
 	SessionCookie string = "JSESSIONID"
 )
 
+// hydraTokenExpiryMargin is the time before the expiry of a Hydra access token at which it is refreshed
+var hydraTokenExpiryMargin = 30 * time.Second
+
 type HydraTokenBody struct {
 	AccessToken string `json:"access_token,omitempty"`
 	ExpiresIn   int    `json:"expires_in,omitempty"`
@@ -43,7 +73,7 @@ func (c *APIClient) GetJwtToken(hydraToken string) (string, error) {
 	// Execute the request
 	response, err := request.Do()
 	if err != nil {
-		return "", fmt.Errorf("request to exchange for auth token %v failed: %s", request, err)
+		return "", fmt.Errorf("request to exchange for auth token %v failed: %w", request, err)
 	}
 	if response.statusCode == 401 {
 		// When we receive the 401 status code, means that the credentials are not valid.
@@ -66,11 +96,32 @@ func (c *APIClient) GetJwtToken(hydraToken string) (string, error) {
 	return response.body, nil
 }
 
+// GetHydraAccessToken gets an access token from Hydra service.
+// The token is cached until shortly before it expires, and concurrent callers share a single refresh.
 func (c *APIClient) GetHydraAccessToken() (string, error) {
 	if c.ClientId == "" || c.ClientSecret == "" {
 		glog.V(4).Infof("The client id or client secret are not provided")
 		return "", nil
 	}
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	if c.hydraToken != "" && time.Now().Before(c.hydraTokenExpiry) {
+		return c.hydraToken, nil
+	}
+	hydraToken, err := c.requestHydraAccessToken()
+	if err != nil || hydraToken.AccessToken == "" {
+		return "", err
+	}
+	if hydraToken.ExpiresIn > 0 {
+		c.hydraToken = hydraToken.AccessToken
+		c.hydraTokenExpiry = time.Now().Add(time.Duration(hydraToken.ExpiresIn)*time.Second - hydraTokenExpiryMargin)
+	}
+	return hydraToken.AccessToken, nil
+}
+
+// requestHydraAccessToken requests a new access token from Hydra service.
+// An empty token without error is returned if the security feature is not available.
+func (c *APIClient) requestHydraAccessToken() (*HydraTokenBody, error) {
 	// Create the form-data format payload
 	payload := &bytes.Buffer{}
 	writer := multipart.NewWriter(payload)
@@ -79,7 +130,7 @@ func (c *APIClient) GetHydraAccessToken() (string, error) {
 	writer.WriteField("grant_type", "client_credentials")
 	err := writer.Close()
 	if err != nil {
-		return "", fmt.Errorf("failed to Close the writer: %v", err)
+		return nil, fmt.Errorf("failed to Close the writer: %v", err)
 	}
 	// Create the rest api request
 	// the format of get hydra access token requires the following in the body, as form-data format
@@ -90,20 +141,20 @@ func (c *APIClient) GetHydraAccessToken() (string, error) {
 	// Execute the request
 	response, err := request.Do()
 	if err != nil {
-		return "", fmt.Errorf("failed to get hydra access token: %s", err)
+		return nil, fmt.Errorf("failed to get hydra access token: %s", err)
 	}
 	if response.statusCode == 401 {
 		// When we receive the 401 status code, means that the credentials are not valid.
 		// We return error, so getJwtToken() method in tap_service will continue
 		// to retry authentication until the credentials are corrected
-		return "", fmt.Errorf("Hydra service authentication failed using the given client_id and secret. " +
+		return nil, fmt.Errorf("Hydra service authentication failed using the given client_id and secret. " +
 			"Redeploy the secret containing the correct credentials and restart the probe pod")
 	}
 	if response.statusCode == 502 {
 		// When we receive the 502 status code, meaning the hydra service is currently not available.
 		// We return error, so getJwtToken() method in tap_service will continue
 		// to retry authentication until the service is restored
-		return "", fmt.Errorf("Hydra service is not available [%v:%s]", response.statusCode, response.status)
+		return nil, fmt.Errorf("Hydra service is not available [%v:%s]", response.statusCode, response.status)
 	}
 	if response.statusCode == 403 {
 		// When we receive the 403 status code, means that the hydra service is currently not available,
@@ -112,54 +163,129 @@ func (c *APIClient) GetHydraAccessToken() (string, error) {
 		// In the case above, there'll be client_id and secret in the k8s secret, but we shouldn't use them in websocket connection
 		// If the hydra service is temporarily not accessible, we have retry in performWebSocketConnection in turbo-go-sdk
 		glog.Errorf("Hydra service is not accessible or disabled [%v:%s]", response.statusCode, response.status)
-		return "", nil
+		return &HydraTokenBody{}, nil
 	}
 	var hydraToken HydraTokenBody
 	err = json.Unmarshal([]byte(response.body), &hydraToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshall get hydra token response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshall get hydra token response: %v", err)
 	}
-	return hydraToken.AccessToken, nil
+	return &hydraToken, nil
 }
 
-// Discover a target using API
-// This function is called by turboctl which is not being maintained
-func (c *APIClient) DiscoverTarget(uuid string) (*Result, error) {
-	response, err := c.Post().Resource(api.Resource_Type_Targets).Name(uuid).Do()
+// DiscoverTarget triggers the rediscovery of a target via api service and returns its discovery status,
+// which holds the time of the preceding discovery to pass to WaitForDiscovery
+func (c *APIClient) DiscoverTarget(uuid string) (*DiscoveryStatus, error) {
+	previous, err := c.GetDiscoveryStatus(uuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover target %s: %w", uuid, err)
+	}
+	newRequest := func() *Request {
+		return c.Post().Resource(api.Resource_Type_Targets).Name(uuid).
+			Param("rediscover", "true").
+			Header("Accept", "application/json")
+	}
+
+	glog.V(4).Infof("[DiscoverTarget] %v.", newRequest())
+
+	response, err := c.doWithSession(newRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover target %s: %s", uuid, err)
 	}
 	if response.statusCode != 200 {
-		return nil, buildResponseError("target discovery", response.status, response.body)
+		return nil, buildStatusError("target discovery", response.statusCode, response.status, response.body)
+	}
+	var target api.Target
+	if err := json.Unmarshal([]byte(response.body), &target); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall target discovery response: %v", err)
 	}
-	return &response, nil
+	status := newDiscoveryStatus(uuid, target.Status, target.LastValidated)
+	status.PreviousValidated = previous.LastValidated
+	return status, nil
 }
 
-// AddTarget adds a target via api service
-func (c *APIClient) AddTarget(target *api.Target) error {
-	// Login first
-	if _, err := c.login(); err != nil {
-		return fmt.Errorf("failed to login: %v", err)
+// GetDiscoveryStatus gets the validation and discovery status of a target via api service
+func (c *APIClient) GetDiscoveryStatus(uuid string) (*DiscoveryStatus, error) {
+	response, err := c.doWithSession(func() *Request {
+		return c.Get().Resource(api.Resource_Type_Targets).Name(uuid).
+			Header("Accept", "application/json")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target %s: %w", uuid, err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("get target", response.statusCode, response.status, response.body)
+	}
+	var target api.Target
+	if err := json.Unmarshal([]byte(response.body), &target); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall get target response: %v", err)
 	}
+	return newDiscoveryStatus(uuid, target.Status, target.LastValidated), nil
+}
+
+// WaitForDiscovery polls the status of a target via api service until its validation and discovery
+// succeeds or fails, or the context is done or the timeout in opts expires. Set opts.Since to the status
+// returned by DiscoverTarget to wait for the discovery it triggered.
+func (c *APIClient) WaitForDiscovery(ctx context.Context, uuid string, opts *DiscoveryWaitOptions) (*DiscoveryStatus, error) {
+	return waitForDiscovery(ctx, uuid, opts, c.GetDiscoveryStatus)
+}
+
+// AddTarget adds a target via api service, or updates it if it already exists with other input fields.
+// An existing target of another probe type is deleted and added again, and restored if adding it fails.
+// It is not deleted if its secrets masked by the server are not among the input fields of the target, since it
+// could not be restored, see ErrSecretsNotRestorable.
+func (c *APIClient) AddTarget(target *api.Target) (TargetOutcome, error) {
 	// Find if the target exists
 	existingTarget, err := c.findTarget(target)
 	if err != nil {
-		return err
+		return "", err
 	}
-	// Update the target if it already exists
-	if existingTarget != nil {
-		glog.V(2).Infof("Target %v already exists.", getTargetId(target))
-		if target.Type == existingTarget.Type {
-			return c.updateTarget(existingTarget, target)
-		}
-		glog.V(2).Infof("Delete and re-add the target since the probe type has changed "+
-			"(old probe type: %v, new probe type: %v, old target: %v, new target: %v)",
-			existingTarget.Type, target.Type, existingTarget, target)
-		if err := c.deleteTarget(existingTarget); err != nil {
-			return fmt.Errorf("failed to delete target %v of type %v which is necessary due to probe type changed"+
-				" to %v; error: %v", existingTarget.DisplayName, existingTarget.Type, target.Type, err)
+	return c.upsertTarget(target, existingTarget)
+}
+
+// upsertTarget creates a target, or updates the existing target matching it if any
+func (c *APIClient) upsertTarget(target, existingTarget *api.Target) (TargetOutcome, error) {
+	if existingTarget == nil {
+		if err := c.createTarget(target); err != nil {
+			return "", err
 		}
+		return TargetCreated, nil
 	}
+	// Update the target if it already exists
+	glog.V(2).Infof("Target %v already exists.", getTargetId(target))
+	if target.Type == existingTarget.Type {
+		return c.updateTarget(existingTarget, target)
+	}
+	// Snapshot the existing target to restore it if adding the target fails; the secrets masked by the server
+	// are restored from the ones of the added target, and the target is not deleted if they cannot be
+	snapshot := &api.Target{
+		Category:    existingTarget.Category,
+		Type:        existingTarget.Type,
+		InputFields: keepMaskedSecrets(target.InputFields, existingTarget.InputFields),
+	}
+	if err := checkRestorable(snapshot, target); err != nil {
+		return "", err
+	}
+	if err := confirmRecreate(c.RecreateConfirmation, existingTarget, target); err != nil {
+		return "", err
+	}
+	glog.V(2).Infof("Delete and re-add the target since the probe type has changed "+
+		"(old probe type: %v, new probe type: %v, old target: %v, new target: %v)",
+		existingTarget.Type, target.Type, existingTarget, target)
+	if err := c.deleteTarget(existingTarget); err != nil {
+		return "", fmt.Errorf("failed to delete target %v of type %v which is necessary due to probe type changed"+
+			" to %v; error: %v", existingTarget.DisplayName, existingTarget.Type, target.Type, err)
+	}
+	if err := c.createTarget(target); err != nil {
+		glog.Warningf("Failed to add target %v of type %v, restoring it with type %v: %v",
+			getTargetId(target), target.Type, snapshot.Type, err)
+		return "", recreateError(existingTarget, target, err, c.createTarget(snapshot))
+	}
+	return TargetRecreated, nil
+}
+
+// createTarget creates a target via api service
+func (c *APIClient) createTarget(target *api.Target) error {
 	// Construct the Target required by the rest api
 	targetData, err := json.Marshal(target)
 	if err != nil {
@@ -167,35 +293,43 @@ func (c *APIClient) AddTarget(target *api.Target) error {
 	}
 
 	// Create the rest api request
-	request := c.Post().Resource(api.Resource_Type_Targets).
-		Header("Content-Type", "application/json").
-		Header("Accept", "application/json").
-		Header("Cookie", fmt.Sprintf("%s=%s", c.SessionCookie.Name, c.SessionCookie.Value)).
-		Data(targetData)
+	newRequest := func() *Request {
+		return c.Post().Resource(api.Resource_Type_Targets).
+			Header("Content-Type", "application/json").
+			Header("Accept", "application/json").
+			Data(targetData)
+	}
 
-	glog.V(4).Infof("[AddTarget] %v.", request)
+	glog.V(4).Infof("[AddTarget] %v.", newRequest())
 	glog.V(4).Infof("[AddTarget] Data: %s.", targetData)
 
 	// Execute the request
-	response, err := request.Do()
+	response, err := c.doWithSession(newRequest)
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return fmt.Errorf("request %v failed: %w", newRequest(), err)
 	}
 	glog.V(4).Infof("Response %+v.", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target addition", response.status, response.body)
+		return buildStatusError("target addition", response.statusCode, response.status, response.body)
 	}
 
 	glog.V(2).Infof("Successfully added target via API service: %v.", response)
 	return nil
 }
 
-// Login to the Turbo API server
-func (c *APIClient) login() (*Result, error) {
-	if c.SessionCookie != nil {
+// Login to the Turbo API server and return the session cookie.
+// Concurrent calls are serialized, so that only the first one logs in when there is no session yet.
+func (c *APIClient) login() (*http.Cookie, error) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.sessionCookie == nil && c.SessionCookie != nil {
+		// The session cookie was set by the caller
+		c.sessionCookie = c.SessionCookie
+	}
+	if c.sessionCookie != nil {
 		// Already logged in
-		return nil, nil
+		return c.sessionCookie, nil
 	}
 	if c.basicAuth == nil ||
 		c.basicAuth.username == "" ||
@@ -210,23 +344,170 @@ func (c *APIClient) login() (*Result, error) {
 
 	response, err := request.Do()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to login  %s: %s", c.baseURL, err)
+		return nil, fmt.Errorf("Failed to login  %s: %w", c.baseURL, err)
 	}
 	if response.statusCode != 200 {
-		return nil, buildResponseError("Turbo server login", response.status, response.body)
+		return nil, buildStatusError("Turbo server login", response.statusCode, response.status, response.body)
 	}
 
 	// Save the session cookie
 	sessionCookie, ok := response.cookies[SessionCookie]
 	if ok {
-		c.SessionCookie = sessionCookie
+		c.sessionCookie, c.SessionCookie = sessionCookie, sessionCookie
 		glog.V(2).Infof("Successfully logged in to Turbonomic server.")
-		glog.V(4).Infof("Session Cookie = %s:%s.", c.SessionCookie.Name, c.SessionCookie.Value)
+		glog.V(4).Infof("Session Cookie = %s:%s.", c.sessionCookie.Name, c.sessionCookie.Value)
 	} else {
 		return nil, buildResponseError("Invalid session cookie", response.status,
 			fmt.Sprintf("%s", response.cookies))
 	}
-	return &response, nil
+	return sessionCookie, nil
+}
+
+// GetSessionCookie returns the cookie of the current login session, or nil if the client has not logged in
+func (c *APIClient) GetSessionCookie() *http.Cookie {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	return c.sessionCookie
+}
+
+// invalidateSession discards the given session cookie if it is still the current one, so that the next
+// request logs in again. Concurrent requests rejected with the same expired session thus trigger a single login.
+func (c *APIClient) invalidateSession(expired *http.Cookie) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.sessionCookie == expired {
+		glog.V(2).Infof("Session to Turbonomic server has expired.")
+		c.sessionCookie, c.SessionCookie = nil, nil
+	}
+}
+
+// doWithSession executes the request built by newRequest with the session cookie, logging in first
+// if there is no session yet. When the session is rejected, e.g. because it has expired, the client
+// logs in again and executes a newly built request once more. An unauthenticated client executes the
+// request without a session.
+func (c *APIClient) doWithSession(newRequest func() *Request) (Result, error) {
+	if c.Unauthenticated {
+		return newRequest().Do()
+	}
+	for attempt := 1; ; attempt++ {
+		cookie, err := c.login()
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to login: %w", err)
+		}
+		response, err := newRequest().
+			Header("Cookie", fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)).
+			Do()
+		if err != nil || response.statusCode != 401 || attempt > 1 {
+			return response, err
+		}
+		c.invalidateSession(cookie)
+	}
+}
+
+// streamWithSession is the streaming variant of doWithSession
+func (c *APIClient) streamWithSession(newRequest func() *Request) (*StreamResult, error) {
+	if c.Unauthenticated {
+		return newRequest().Stream()
+	}
+	for attempt := 1; ; attempt++ {
+		cookie, err := c.login()
+		if err != nil {
+			return nil, fmt.Errorf("failed to login: %w", err)
+		}
+		response, err := newRequest().
+			Header("Cookie", fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)).
+			Stream()
+		if err != nil || response.StatusCode != 401 || attempt > 1 {
+			return response, err
+		}
+		response.Close()
+		c.invalidateSession(cookie)
+	}
+}
+
+// doJSON executes the request built by newRequest with the session cookie. The input, if not nil,
+// is sent as the JSON body of the request, and the JSON body of the response is parsed into the output,
+// if not nil.
+func (c *APIClient) doJSON(requestDesc string, newRequest func() *Request, input, output interface{}) error {
+	var data []byte
+	if input != nil {
+		var err error
+		if data, err = json.Marshal(input); err != nil {
+			return fmt.Errorf("failed to marshall %s input: %v", requestDesc, err)
+		}
+	}
+	request := func() *Request {
+		request := newRequest().Header("Accept", "application/json")
+		if data != nil {
+			request.Header("Content-Type", "application/json").Data(data)
+		}
+		return request
+	}
+	glog.V(4).Infof("[%s] %v.", requestDesc, request())
+	if data != nil {
+		glog.V(4).Infof("[%s] Data: %s.", requestDesc, data)
+	}
+
+	response, err := c.doWithSession(request)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", requestDesc, err)
+	}
+	glog.V(4).Infof("Response %+v.", response)
+	if response.statusCode < 200 || response.statusCode >= 300 {
+		return buildStatusError(requestDesc, response.statusCode, response.status, response.body)
+	}
+	if output == nil || response.body == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(response.body), output); err != nil {
+		return fmt.Errorf("failed to unmarshall %s response: %v", requestDesc, err)
+	}
+	return nil
+}
+
+// errStopList is returned by the decode function of streamList to stop streaming without an error
+var errStopList = errors.New("stop streaming list")
+
+// streamList streams the JSON array returned by the request built by newRequest and calls decode
+// for each element. Pages are followed using the cursor returned in the X-Next-Cursor header.
+func (c *APIClient) streamList(requestDesc string, newRequest func() *Request,
+	decode func(decoder *JSONArrayDecoder) error) error {
+	cursor := ""
+	for {
+		request := func() *Request {
+			request := newRequest().Header("Accept", "application/json")
+			if cursor != "" {
+				request.Param("cursor", cursor)
+			}
+			return request
+		}
+		response, err := c.streamWithSession(request)
+		if err != nil {
+			return fmt.Errorf("%s request failed: %w", requestDesc, err)
+		}
+		if response.StatusCode != 200 {
+			return response.Error(requestDesc)
+		}
+		decoder := NewJSONArrayDecoder(response.Body)
+		for decoder.More() {
+			if err = decode(decoder); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = decoder.Err()
+		}
+		response.Close()
+		if err == errStopList {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unmarshall %s response: %v", requestDesc, err)
+		}
+		if cursor = response.Header.Get("X-Next-Cursor"); cursor == "" {
+			return nil
+		}
+	}
 }
 
 func (c *APIClient) printTarget(description string, target *api.Target) {
@@ -236,110 +517,140 @@ func (c *APIClient) printTarget(description string, target *api.Target) {
 	}
 }
 
+// listTargets lists all the targets
+func (c *APIClient) listTargets() ([]api.Target, error) {
+	var targets []api.Target
+	if err := c.streamList("list targets", func() *Request {
+		return c.Get().Resource(api.Resource_Type_Targets)
+	}, func(decoder *JSONArrayDecoder) error {
+		var target api.Target
+		if err := decoder.Decode(&target); err != nil {
+			return err
+		}
+		targets = append(targets, target)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
 func (c *APIClient) findTarget(target *api.Target) (*api.Target, error) {
 	c.printTarget("Find target", target)
 
 	// Get a list of targets from the Turbo server
-	request := c.Get().Resource(api.Resource_Type_Targets).
-		Header("Content-Type", "application/json").
-		Header("Accept", "application/json").
-		Header("Cookie", fmt.Sprintf("%s=%s", c.SessionCookie.Name, c.SessionCookie.Value))
+	newRequest := func() *Request {
+		return c.Get().Resource(api.Resource_Type_Targets).
+			Header("Content-Type", "application/json").
+			Header("Accept", "application/json")
+	}
 
-	response, err := request.Do()
+	// Stream the response since the list of targets can be large
+	response, err := c.streamWithSession(newRequest)
 	if err != nil {
 		glog.Errorf("Failed to execute find target request: %s.", err)
-		return nil, fmt.Errorf("failed to execute find target request: %v", err)
+		return nil, fmt.Errorf("failed to execute find target request: %w", err)
 	}
 
-	glog.V(4).Infof("Received response from find target request %v: %+v.",
-		request, response)
-
-	if response.statusCode != 200 {
-		return nil, buildResponseError("find target", response.status, response.body)
-	}
+	glog.V(4).Infof("Received response from find target request %v: %v.",
+		newRequest(), response.Status)
 
-	// Parse the response - list of targets
-	var targetList []api.Target
-	if err := json.Unmarshal([]byte(response.body), &targetList); err != nil {
-		return nil, fmt.Errorf("failed to unmarshall find target response: %v", err)
+	if response.StatusCode != 200 {
+		return nil, response.Error("find target")
 	}
+	defer response.Close()
 
 	// The target identifier for the given target
 	targetId := getTargetId(target)
 
 	// Iterate over the list of targets to look for the given target
-	// by comparing the category, target type and identifier fields
-	// target type is regarded the same if the old one only differs by an extra suffix
-	for _, tgt := range targetList {
+	decoder := NewJSONArrayDecoder(response.Body)
+	for decoder.More() {
+		var tgt api.Target
+		if err := decoder.Decode(&tgt); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall find target response: %v", err)
+		}
 		c.printTarget("Trying to match with target", &tgt)
-		// array of InputFields
-		for _, inputField := range tgt.InputFields {
-			if inputField.Name == "targetIdentifier" &&
-				inputField.Value == targetId &&
-				tgt.Category == target.Category &&
-				strings.HasPrefix(tgt.Type, target.Type) {
-				glog.V(4).Infof("Found target match")
-				return &tgt, nil
-			}
+		if matchTarget(c.TargetMatcher, &tgt, target) {
+			glog.V(4).Infof("Found target match")
+			return &tgt, nil
 		}
 	}
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall find target response: %v", err)
+	}
 
 	glog.V(4).Infof("target %v does not exist", targetId)
 	return nil, nil
 }
 
-func (c *APIClient) updateTarget(existing, input *api.Target) error {
+// updateTarget updates the input fields of an existing target, unless they are already the desired ones
+func (c *APIClient) updateTarget(existing, input *api.Target) (TargetOutcome, error) {
+	diff := DiffInputFields(existing.InputFields, input.InputFields)
+	if diff.Empty() {
+		glog.V(2).Infof("Target %v is unchanged.", getTargetId(input))
+		return TargetUnchanged, nil
+	}
+	if c.SkipMaskedSecrets && len(diff.Changed) == 0 {
+		glog.V(2).Infof("Target %v is not updated although its masked secrets %v may have changed.",
+			getTargetId(input), diff.MaskedSecrets)
+		return TargetSecretsUnverified, nil
+	}
+	glog.V(4).Infof("Updating target %v with changed fields %v and masked secrets %v.",
+		getTargetId(input), diff.Changed, diff.MaskedSecrets)
 	// Update the input fields
 	existing.InputFields = input.InputFields
 	targetData, err := json.Marshal(existing)
 	if err != nil {
-		return fmt.Errorf("failed to marshall target instance: %v", err)
+		return "", fmt.Errorf("failed to marshall target instance: %v", err)
 	}
 
 	// Create the rest api request
-	request := c.Put().Resource(api.Resource_Type_Targets).Name(existing.UUID).
-		Header("Content-Type", "application/json").
-		Header("Accept", "application/json").
-		Header("Cookie", fmt.Sprintf("%s=%s", c.SessionCookie.Name, c.SessionCookie.Value)).
-		Data(targetData)
+	newRequest := func() *Request {
+		return c.Put().Resource(api.Resource_Type_Targets).Name(existing.UUID).
+			Header("Content-Type", "application/json").
+			Header("Accept", "application/json").
+			Data(targetData)
+	}
 
-	glog.V(4).Infof("[UpdateTarget] %v.", request)
+	glog.V(4).Infof("[UpdateTarget] %v.", newRequest())
 	glog.V(4).Infof("[UpdateTarget] Data: %s.", targetData)
 
 	// Execute the request
-	response, err := request.Do()
+	response, err := c.doWithSession(newRequest)
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return "", fmt.Errorf("request %v failed: %w", newRequest(), err)
 	}
 	glog.V(4).Infof("Response %+v.", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target update", response.status, response.body)
+		return "", buildStatusError("target update", response.statusCode, response.status, response.body)
 	}
 
 	glog.V(2).Infof("Successfully updated target via API service.")
-	return nil
+	return TargetUpdated, nil
 }
 
 // deleteTarget deletes an existing target
 func (c *APIClient) deleteTarget(existing *api.Target) error {
 	// Create the rest api request
-	request := c.Delete().Resource(api.Resource_Type_Targets).Name(existing.UUID).
-		Header("Content-Type", "application/json").
-		Header("Accept", "application/json").
-		Header("Cookie", fmt.Sprintf("%s=%s", c.SessionCookie.Name, c.SessionCookie.Value))
+	newRequest := func() *Request {
+		return c.Delete().Resource(api.Resource_Type_Targets).Name(existing.UUID).
+			Header("Content-Type", "application/json").
+			Header("Accept", "application/json")
+	}
 
-	glog.V(4).Infof("[DeleteTarget] %v.", request)
+	glog.V(4).Infof("[DeleteTarget] %v.", newRequest())
 
 	// Execute the request
-	response, err := request.Do()
+	response, err := c.doWithSession(newRequest)
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return fmt.Errorf("request %v failed: %w", newRequest(), err)
 	}
 	glog.V(4).Infof("Response %+v.", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target delete", response.status, response.body)
+		return buildStatusError("target delete", response.statusCode, response.status, response.body)
 	}
 
 	glog.V(2).Infof("Successfully deleted target %v of type %v via API service.",
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+const concurrency = 20
+
+func TestTurboClient_ConcurrentAddTarget(t *testing.T) {
+	for _, service := range []string{API, TopologyProcessor} {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("Kubernetes", "Cloud Native")
+		turboClient := newTestTurboClient(t, server)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, concurrency)
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := turboClient.AddTarget(newTestTarget("Kubernetes", fmt.Sprintf("cluster-%d", i), "p"), service)
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err, service)
+		}
+		assert.Equal(t, concurrency, len(server.Targets()), service)
+		if service == API {
+			assert.Equal(t, 1, server.RequestCount("POST", "/vmturbo/rest/login"))
+		}
+		server.Close()
+	}
+}
+
+func TestAPIClient_SessionExpiry(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient := newTestTurboClient(t, server)
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.NoError(t, err)
+	uuid := server.Targets()[0].UUID
+
+	client, _ := turboClient.getClient(API)
+	apiClient := client.(*APIClient)
+	expiredCookie := apiClient.GetSessionCookie()
+	assert.NotNil(t, expiredCookie)
+
+	// All the concurrent requests are rejected with the expired session, but only one logs in again
+	server.ExpireSessions()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := apiClient.GetDiscoveryStatus(uuid)
+			assert.NoError(t, err)
+			assert.Equal(t, DiscoverySucceeded, status.State)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, server.RequestCount("POST", "/vmturbo/rest/login"))
+	assert.NotEqual(t, expiredCookie, apiClient.GetSessionCookie())
+
+	// The deprecated field follows the session
+	assert.Equal(t, apiClient.GetSessionCookie(), apiClient.SessionCookie)
+
+	// A session cookie set beforehand is used without logging in
+	otherClient := &APIClient{RESTClient: NewRESTClient(http.DefaultClient, server.URL(), APIPath),
+		SessionCookie: apiClient.GetSessionCookie()}
+	_, err = otherClient.GetDiscoveryStatus(uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.RequestCount("POST", "/vmturbo/rest/login"))
+
+	// The session is rejected again after logging in
+	server.InjectFault("GET", "/vmturbo/rest/targets/"+uuid, fake.Fault{StatusCode: 401})
+	_, err = apiClient.GetDiscoveryStatus(uuid)
+	assert.Error(t, err)
+	assert.Equal(t, 3, server.RequestCount("POST", "/vmturbo/rest/login"))
+}
+
+func TestAPIClient_Unauthenticated(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		RegisterService("unauthenticated", ServiceEndpoint{Path: APIPath, Unauthenticated: true}).Create())
+
+	// The requests are sent without logging in
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), "unauthenticated")
+	assert.Error(t, err)
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/login"))
+	assert.Equal(t, 1, server.RequestCount("GET", "/vmturbo/rest/targets"))
+	assert.Empty(t, server.Targets())
+}
+
+func TestAPIClient_ConcurrentGetHydraAccessToken(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.SetClientCredentials("id", "secret")
+	config := NewConfigBuilder(server.URL()).SetClientId("id").SetClientSecret("secret").Create()
+	turboClient, _ := NewTurboClient(config)
+
+	var wg sync.WaitGroup
+	tokens := make(chan string, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := turboClient.GetHydraAccessToken()
+			assert.NoError(t, err)
+			tokens <- token
+		}()
+	}
+	wg.Wait()
+	close(tokens)
+	first := <-tokens
+	assert.NotEmpty(t, first)
+	for token := range tokens {
+		assert.Equal(t, first, token)
+	}
+	assert.Equal(t, 1, server.RequestCount("POST", "/oauth2/token"))
+
+	jwt, err := turboClient.GetJwtToken(first)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, jwt)
+}
+
+func TestAPIClient_GetHydraAccessTokenFailure(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.SetClientCredentials("id", "secret")
+	config := NewConfigBuilder(server.URL()).SetClientId("id").SetClientSecret("secret").Create()
+	turboClient, _ := NewTurboClient(config)
+
+	// Failures are not cached
+	server.InjectFault("POST", "/oauth2/token", fake.Fault{StatusCode: 502, Times: 1})
+	_, err := turboClient.GetHydraAccessToken()
+	assert.Error(t, err)
+	token, err := turboClient.GetHydraAccessToken()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, 2, server.RequestCount("POST", "/oauth2/token"))
+}
+
+// newTestAPIClient builds an api service client to a fake server, which passes the requests to the given
+// resources of the api service to the given handler
+func newTestAPIClient(handler http.HandlerFunc, resources ...string) (*APIClient, *fake.Server) {
+	server := fake.NewServer("foo", "bar")
+	for _, resource := range resources {
+		server.HandleAPI(resource, handler)
+	}
+	return &APIClient{
+		RESTClient: NewRESTClient(server.Client(), server.URL(), APIPath).
+			BasicAuthentication(&BasicAuthentication{"foo", "bar"}),
+	}, server
+}
+
+func TestAPIClient_doJSON(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scenarios":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			fmt.Fprintf(w, `{"uuid":"1","displayName":%q}`, strings.Trim(string(body), "\""))
+		case "/malformed":
+			fmt.Fprint(w, `{"uuid":`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found"}`)
+		}
+	}, "scenarios", "malformed", "missing")
+	defer server.Close()
+
+	var output api.Scenario
+	assert.NoError(t, apiClient.doJSON("create", func() *Request {
+		return apiClient.Post().Resource(api.Resource_Type_Scenarios)
+	}, "foo", &output))
+	assert.Equal(t, api.Scenario{UUID: "1", DisplayName: "foo"}, output)
+
+	assert.Error(t, apiClient.doJSON("malformed", func() *Request {
+		return apiClient.Get().Resource("malformed")
+	}, nil, &output))
+	assert.EqualError(t, apiClient.doJSON("missing", func() *Request {
+		return apiClient.Get().Resource("missing")
+	}, nil, nil), "unsuccessful missing response: 404 Not Found. not found.")
+}
+
+func TestAPIClient_streamList(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("X-Next-Cursor", "2")
+			fmt.Fprint(w, `[{"uuid":"0"},{"uuid":"1"}]`)
+		case "2":
+			w.Header().Set("X-Next-Cursor", "3")
+			fmt.Fprint(w, `[{"uuid":"2"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}, "entities")
+	defer server.Close()
+
+	var uuids []string
+	assert.NoError(t, apiClient.streamList("list", func() *Request {
+		return apiClient.Get().Resource("entities")
+	}, func(decoder *JSONArrayDecoder) error {
+		var entity api.ServiceEntity
+		if err := decoder.Decode(&entity); err != nil {
+			return err
+		}
+		uuids = append(uuids, entity.UUID)
+		return nil
+	}))
+	assert.Equal(t, []string{"0", "1", "2"}, uuids)
+}
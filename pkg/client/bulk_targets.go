@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+var defaultAddTargetsConcurrency = 4
+
+// AddTargetsOptions configures the addition of targets in bulk
+type AddTargetsOptions struct {
+	// Service the targets are added via, API or TopologyProcessor; chosen like AddTargetAuto does if empty
+	Service string
+	// Maximum number of targets added concurrently, defaults to 4
+	Concurrency int
+}
+
+// TargetResult is the result of adding one of the targets in bulk
+type TargetResult struct {
+	Target *api.Target
+	// Whether the target was created, updated, left unchanged or recreated, if it was added
+	Outcome TargetOutcome
+	// The reason the target was not added, if any
+	Err error
+}
+
+// AddTargetsReport reports the addition of targets in bulk
+type AddTargetsReport struct {
+	// Service the targets were added via
+	Service string
+	// The reason topology processor was not used, if the service was chosen automatically
+	FallbackReason string
+	// Results of the targets, in the order they were given
+	Results []TargetResult
+}
+
+// Failed returns the results of the targets which were not added
+func (r *AddTargetsReport) Failed() []TargetResult {
+	var failed []TargetResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error summarizing the targets which were not added, or nil if all the targets were added
+func (r *AddTargetsReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	var reasons []string
+	for _, result := range failed {
+		reasons = append(reasons, fmt.Sprintf("%s: %v", getTargetId(result.Target), result.Err))
+	}
+	return fmt.Errorf("failed to add %d of %d targets: %s", len(failed), len(r.Results), strings.Join(reasons, "; "))
+}
+
+// targetUpsert adds or updates a target matched with the existing targets beforehand
+type targetUpsert func() (TargetOutcome, error)
+
+// bulkTargetAdder is a client which can add targets in bulk
+type bulkTargetAdder interface {
+	// prepareUpserts lists the existing targets once and matches them with the given targets,
+	// returning the upsert of each of the given targets
+	prepareUpserts(targets []*api.Target) ([]targetUpsert, error)
+}
+
+// AddTargets adds targets, or updates the ones which already exist, listing the existing targets once and adding
+// the targets concurrently. The failures of individual targets are reported in the returned report, see
+// AddTargetsReport.Err, while an error is returned if no target can be added, i.e. if the targets cannot be listed.
+// The targets which are not started when the context is done fail with the error of the context.
+func (turboClient *TurboClient) AddTargets(ctx context.Context, targets []*api.Target,
+	opts *AddTargetsOptions) (*AddTargetsReport, error) {
+	concurrency := defaultAddTargetsConcurrency
+	report := &AddTargetsReport{Results: make([]TargetResult, len(targets))}
+	if opts != nil {
+		report.Service = opts.Service
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+	}
+	if report.Service == "" {
+		report.Service = TopologyProcessor
+		if reason := turboClient.topologyProcessorUnavailable(); reason != "" {
+			report.Service = API
+			report.FallbackReason = reason
+		}
+	}
+	client, err := turboClient.getClient(report.Service)
+	if err != nil {
+		return nil, err
+	}
+	adder, ok := client.(bulkTargetAdder)
+	if !ok {
+		return nil, fmt.Errorf("client for service %v cannot add targets in bulk", report.Service)
+	}
+
+	// Check the targets before matching them
+	var pending []int
+	var pendingTargets []*api.Target
+	for i, target := range targets {
+		report.Results[i].Target = target
+		if err := turboClient.checkTarget(target); err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+		pendingTargets = append(pendingTargets, target)
+	}
+	if len(pending) == 0 {
+		return report, nil
+	}
+	upserts, err := adder.prepareUpserts(pendingTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the targets with bounded parallelism
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for k, i := range pending {
+		if ctx.Err() == nil {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			report.Results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(upsert targetUpsert, result *TargetResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			result.Outcome, result.Err = upsert()
+		}(upserts[k], &report.Results[i])
+	}
+	wg.Wait()
+	glog.V(2).Infof("Added %d targets via %s service, %d failed.",
+		len(targets), report.Service, len(report.Failed()))
+	return report, nil
+}
+
+// prepareUpserts lists the existing targets via api service once and matches them with the given targets
+func (c *APIClient) prepareUpserts(targets []*api.Target) ([]targetUpsert, error) {
+	existingTargets, err := c.listTargets()
+	if err != nil {
+		return nil, err
+	}
+	upserts := make([]targetUpsert, len(targets))
+	for i, target := range targets {
+		if err := checkDuplicateTarget(c.TargetMatcher, targets, i); err != nil {
+			upserts[i] = failedUpsert(err)
+			continue
+		}
+		var existingTarget *api.Target
+		for j := range existingTargets {
+			if matchTarget(c.TargetMatcher, &existingTargets[j], target) {
+				existingTarget = &existingTargets[j]
+				break
+			}
+		}
+		target := target
+		upserts[i] = func() (TargetOutcome, error) {
+			return c.upsertTarget(target, existingTarget)
+		}
+	}
+	return upserts, nil
+}
+
+// prepareUpserts lists the probes and the existing targets via topology processor service once
+// and matches them with the given targets
+func (c *TPClient) prepareUpserts(targets []*api.Target) ([]targetUpsert, error) {
+	probeList, err := c.GetProbes()
+	if err != nil {
+		return nil, err
+	}
+	if !hasProbes(probeList, targets) {
+		// The probes may have been registered since the probes were cached
+		if probeList, err = c.listProbes(false); err != nil {
+			return nil, err
+		}
+	}
+	probes := make(map[int64]*api.ProbeDescription, len(probeList))
+	for i := range probeList {
+		probes[probeList[i].ID] = &probeList[i]
+	}
+	existingTargets, err := c.GetTargets()
+	if err != nil {
+		return nil, err
+	}
+	upserts := make([]targetUpsert, len(targets))
+	identified := make([]*api.Target, len(targets))
+	for i, target := range targets {
+		var probe *api.ProbeDescription
+		for j := range probeList {
+			if probeList[j].Category == target.Category && probeList[j].Type == target.Type {
+				probe = &probeList[j]
+				break
+			}
+		}
+		if probe == nil {
+			upserts[i] = failedUpsert(fmt.Errorf("failed to find probe with category %v and type %v",
+				target.Category, target.Type))
+			continue
+		}
+		target := identifyTarget(target, probe)
+		identified[i] = target
+		if err := checkDuplicateTarget(c.TargetMatcher, identified, i); err != nil {
+			upserts[i] = failedUpsert(err)
+			continue
+		}
+		var existingTarget *api.TargetInfo
+		for j := range existingTargets {
+			if existingTargets[j].TargetSpec != nil &&
+				matchTarget(c.TargetMatcher, tpTarget(&existingTargets[j], probes), target) {
+				existingTarget = &existingTargets[j]
+				break
+			}
+		}
+		upserts[i] = func() (TargetOutcome, error) {
+			return c.upsertTarget(target, probe, probes, existingTarget)
+		}
+	}
+	return upserts, nil
+}
+
+// hasProbes returns true if the probes of all the targets are in the given list
+func hasProbes(probes []api.ProbeDescription, targets []*api.Target) bool {
+	for _, target := range targets {
+		if !hasProbe(probes, target.Type, target.Category) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDuplicateTarget returns an error if the i-th target matches one of the targets before it,
+// which would be added twice otherwise
+func checkDuplicateTarget(matcher TargetMatcher, targets []*api.Target, i int) error {
+	for j := 0; j < i; j++ {
+		if targets[j] != nil && matchTarget(matcher, targets[j], targets[i]) {
+			return fmt.Errorf("target %v is given more than once", getTargetId(targets[i]))
+		}
+	}
+	return nil
+}
+
+// failedUpsert returns an upsert failing with the given error
+func failedUpsert(err error) targetUpsert {
+	return func() (TargetOutcome, error) {
+		return "", err
+	}
+}
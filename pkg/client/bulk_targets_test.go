@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+func TestTurboClient_AddTargets(t *testing.T) {
+	table := []struct {
+		service     string
+		targetsPath string
+	}{
+		{API, "/vmturbo/rest/targets"},
+		{TopologyProcessor, "/target"},
+	}
+	for _, item := range table {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("Kubernetes-old", "Cloud Native")
+		server.AddProbe("Kubernetes", "Cloud Native")
+		turboClient := newTestTurboClient(t, server)
+		for _, target := range []*api.Target{
+			newTestTarget("Kubernetes", "unchanged", "p"),
+			newTestTarget("Kubernetes", "updated", "p1"),
+			newTestTarget("Kubernetes-old", "recreated", "p"),
+		} {
+			_, err := turboClient.AddTarget(target, item.service)
+			assert.NoError(t, err, item.service)
+		}
+		server.ResetRequests()
+
+		var targets []*api.Target
+		for i := 0; i < 10; i++ {
+			targets = append(targets, newTestTarget("Kubernetes", fmt.Sprintf("cluster-%d", i), "p"))
+		}
+		unchanged := newTestTarget("Kubernetes", "unchanged", "p")
+		if item.service == API {
+			unchanged = newTestTarget("Kubernetes", "unchanged", MaskedSecretValue)
+		}
+		targets = append(targets,
+			unchanged,
+			newTestTarget("Kubernetes", "updated", "p2"),
+			newTestTarget("Kubernetes", "recreated", "p"),
+			newTestTarget("Unknown", "unknown", "p"),
+			newTestTarget("Kubernetes", "cluster-0", "p"))
+		report, err := turboClient.AddTargets(context.Background(), targets,
+			&AddTargetsOptions{Service: item.service, Concurrency: 3})
+		assert.NoError(t, err, item.service)
+		assert.Equal(t, item.service, report.Service)
+		assert.Equal(t, len(targets), len(report.Results), item.service)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, TargetResult{Target: targets[i], Outcome: TargetCreated}, report.Results[i], item.service)
+		}
+		assert.Equal(t, TargetUnchanged, report.Results[10].Outcome, item.service)
+		assert.Equal(t, TargetUpdated, report.Results[11].Outcome, item.service)
+		assert.Equal(t, TargetRecreated, report.Results[12].Outcome, item.service)
+		assert.Error(t, report.Results[13].Err, item.service)
+		assert.EqualError(t, report.Results[14].Err, "target cluster-0 is given more than once", item.service)
+		assert.Equal(t, 2, len(report.Failed()), item.service)
+		assert.Contains(t, report.Err().Error(), "failed to add 2 of 15 targets: unknown: ", item.service)
+		assert.Equal(t, 13, len(server.Targets()), item.service)
+		// The targets are listed once
+		assert.Equal(t, 1, server.RequestCount("GET", item.targetsPath), item.service)
+		server.Close()
+	}
+}
+
+func TestTurboClient_AddTargets_Concurrency(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	server.InjectFault("POST", "/target", fake.Fault{Latency: 50 * time.Millisecond})
+	var lock sync.Mutex
+	checked := 0
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetTargetPreflightCheck(func(turboClient *TurboClient, target *api.Target) error {
+			lock.Lock()
+			defer lock.Unlock()
+			checked++
+			if getTargetId(target) == "rejected" {
+				return errors.New("rejected")
+			}
+			return nil
+		}).Create())
+
+	var targets []*api.Target
+	for i := 0; i < 8; i++ {
+		targets = append(targets, newTestTarget("Kubernetes", fmt.Sprintf("cluster-%d", i), "p"))
+	}
+	targets = append(targets, newTestTarget("Kubernetes", "rejected", "p"))
+	start := time.Now()
+	report, err := turboClient.AddTargets(context.Background(), targets,
+		&AddTargetsOptions{Service: TopologyProcessor, Concurrency: 4})
+	assert.NoError(t, err)
+	// 8 targets are added 4 at a time
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 100*time.Millisecond, "elapsed %v", elapsed)
+	assert.True(t, elapsed < 400*time.Millisecond, "elapsed %v", elapsed)
+	assert.Equal(t, 9, checked)
+	assert.EqualError(t, report.Err(), "failed to add 1 of 9 targets: rejected: "+
+		"preflight check of Kubernetes target rejected failed: rejected")
+	assert.Equal(t, 8, len(server.Targets()))
+
+	// The targets are not added once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = turboClient.AddTargets(ctx, targets[:2], &AddTargetsOptions{Service: TopologyProcessor})
+	assert.NoError(t, err)
+	for _, result := range report.Results {
+		assert.Equal(t, context.Canceled, result.Err)
+	}
+}
+
+func TestTurboClient_AddTargets_Errors(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	turboClient := newTestTurboClient(t, server)
+	targets := []*api.Target{newTestTarget("Kubernetes", "cluster", "p")}
+
+	_, err := turboClient.AddTargets(context.Background(), targets, &AddTargetsOptions{Service: "cost"})
+	assert.EqualError(t, err, "client for service cost is not registered")
+	server.InjectFault("GET", "/vmturbo/rest/targets", fake.Fault{StatusCode: 502})
+	_, err = turboClient.AddTargets(context.Background(), targets, &AddTargetsOptions{Service: API})
+	assert.EqualError(t, err, "unsuccessful list targets response: 502 Bad Gateway.")
+
+	// The service is chosen automatically
+	defer func(f func() bool) { inCluster = f }(inCluster)
+	inCluster = func() bool { return false }
+	server.AddProbe("Kubernetes", "Cloud Native")
+	server.ClearFaults()
+	report, err := turboClient.AddTargets(context.Background(), targets, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, API, report.Service)
+	assert.Equal(t, "the client does not run in a Kubernetes cluster", report.FallbackReason)
+	assert.NoError(t, report.Err())
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// cachedResourceGroups maps the resources whose listings are cached to their group. The resources of a group
+// list the same objects via different services, so writing one of them invalidates the listings of all of them.
+var cachedResourceGroups = map[api.ResourceType]string{
+	api.Resource_Type_Probe:     "probes",
+	api.Resource_Type_Target:    "targets",
+	api.Resource_Type_Targets:   "targets",
+	api.Resource_Type_Templates: "templates",
+}
+
+// maxCachedStreamSize is the maximum size of a streamed response body kept in the cache
+const maxCachedStreamSize = 16 * 1024 * 1024
+
+// ResponseCache caches the listings of probes, targets and templates, which are read far more often than they
+// change. A cached listing is used for the TTL of the cache, then revalidated with its ETag, if any, using
+// If-None-Match. The listings of a resource are invalidated by the requests writing the resource.
+// It is safe for concurrent use by multiple goroutines, and can be shared by the clients of multiple services.
+type ResponseCache struct {
+	ttl time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+	// generations of the groups, incremented when they are invalidated, so that the listings received
+	// while a group is written are not cached
+	generations map[string]uint64
+	now         func() time.Time
+}
+
+type cacheEntry struct {
+	group  string
+	result Result
+	etag   string
+	expiry time.Time
+}
+
+// cacheLookup is the cached entry of a listing request, if any, looked up before the request is sent
+type cacheLookup struct {
+	key        string
+	group      string
+	entry      *cacheEntry
+	fresh      bool
+	generation uint64
+}
+
+// NewResponseCache builds a cache using the cached listings for the given TTL before revalidating them
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:         ttl,
+		entries:     make(map[string]*cacheEntry),
+		generations: make(map[string]uint64),
+		now:         time.Now,
+	}
+}
+
+// Invalidate removes the cached listings of the group of the given resource
+func (c *ResponseCache) Invalidate(resource api.ResourceType) {
+	group, found := cachedResourceGroups[resource]
+	if !found {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generations[group]++
+	for key, entry := range c.entries {
+		if entry.group == group {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Clear removes all the cached listings
+func (c *ResponseCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, group := range cachedResourceGroups {
+		c.generations[group]++
+	}
+	c.entries = make(map[string]*cacheEntry)
+}
+
+// lookup returns the cached entry of a listing of the given group
+func (c *ResponseCache) lookup(key, group string) *cacheLookup {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	lookup := &cacheLookup{key: key, group: group, generation: c.generations[group]}
+	if entry, found := c.entries[key]; found {
+		lookup.entry = entry
+		lookup.fresh = c.now().Before(entry.expiry)
+	}
+	return lookup
+}
+
+// store caches a listing received for a lookup, unless its group was invalidated since the lookup
+func (c *ResponseCache) store(lookup *cacheLookup, result Result) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generations[lookup.group] != lookup.generation {
+		return
+	}
+	result.header = result.header.Clone()
+	result.cookies = nil
+	c.entries[lookup.key] = &cacheEntry{
+		group:  lookup.group,
+		result: result,
+		etag:   result.header.Get("ETag"),
+		expiry: c.now().Add(c.ttl),
+	}
+}
+
+// revalidate uses a cached listing for the TTL again, since the server reported that it is not modified
+func (c *ResponseCache) revalidate(lookup *cacheLookup) {
+	c.store(lookup, lookup.entry.result)
+}
+
+// cacheLookup looks up the cached response of the request, or returns nil if its response is not cached,
+// which is the case of all the requests but the listings of cached resources. The entry of the returned
+// lookup is nil if the request does not use the cached response.
+func (r *Request) cacheLookup() *cacheLookup {
+	if r.cache == nil || r.verb != http.MethodGet || r.resourceName != "" {
+		return nil
+	}
+	group, found := cachedResourceGroups[r.resource]
+	if !found {
+		return nil
+	}
+	lookup := r.cache.lookup(r.URL().String(), group)
+	if r.noCache {
+		// The response is received again, and replaces the cached one
+		lookup.entry, lookup.fresh = nil, false
+	}
+	if lookup.entry != nil && !lookup.fresh && lookup.entry.etag != "" {
+		r.Header("If-None-Match", lookup.entry.etag)
+	}
+	return lookup
+}
+
+// invalidateCache invalidates the cached listings of the resource written by the request, if any
+func (r *Request) invalidateCache() {
+	if r.cache != nil && r.verb != http.MethodGet {
+		r.cache.Invalidate(r.resource)
+	}
+}
+
+// streamResult returns a cached listing as the result of a streamed request
+func (e *cacheEntry) streamResult() *StreamResult {
+	return &StreamResult{
+		StatusCode: e.result.statusCode,
+		Status:     e.result.status,
+		Header:     e.result.header.Clone(),
+		Body:       ioutil.NopCloser(bytes.NewBufferString(e.result.body)),
+	}
+}
+
+// cachingBody is the body of a streamed listing, cached once it is read entirely, unless it exceeds
+// maxCachedStreamSize. The decoders stop reading at the end of the listing, so the rest of the body is read
+// when it is closed.
+type cachingBody struct {
+	io.ReadCloser
+	cache   *ResponseCache
+	lookup  *cacheLookup
+	result  Result
+	content bytes.Buffer
+	done    bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.done {
+		return n, err
+	}
+	b.content.Write(p[:n])
+	if b.content.Len() > maxCachedStreamSize {
+		b.done = true
+		b.content = bytes.Buffer{}
+	} else if err == io.EOF {
+		b.done = true
+		b.result.body = b.content.String()
+		b.cache.store(b.lookup, b.result)
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	if !b.done {
+		io.Copy(ioutil.Discard, io.LimitReader(b, maxCachedStreamSize+1))
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+func TestResponseCache_Listings(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetResponseCacheTTL(time.Minute).Create())
+	tpClient, _ := turboClient.TopologyProcessorClient()
+	now := time.Now()
+	tpClient.cache.now = func() time.Time { return now }
+
+	// The probes and targets are listed once for the TTL
+	for i := 0; i < 3; i++ {
+		probes, err := tpClient.GetProbes()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(probes))
+		targets, err := tpClient.GetTargets()
+		assert.NoError(t, err)
+		assert.Empty(t, targets)
+	}
+	assert.Equal(t, 1, server.RequestCount("GET", "/probe"))
+	assert.Equal(t, 1, server.RequestCount("GET", "/target"))
+	// Ping reaches the server
+	assert.NoError(t, tpClient.Ping(context.Background()))
+	assert.Equal(t, 2, server.RequestCount("GET", "/probe"))
+
+	// Adding a target via api service invalidates the targets listed via both services
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, server.RequestCount("GET", "/vmturbo/rest/targets"))
+	targets, err := tpClient.GetTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, 2, server.RequestCount("GET", "/target"))
+	outcome, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", MaskedSecretValue), API)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetUnchanged, outcome)
+	assert.Equal(t, 2, server.RequestCount("GET", "/vmturbo/rest/targets"))
+	outcome, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", MaskedSecretValue), API)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetUnchanged, outcome)
+	assert.Equal(t, 2, server.RequestCount("GET", "/vmturbo/rest/targets"))
+
+	// The listings are revalidated once expired
+	now = now.Add(2 * time.Minute)
+	server.ResetRequests()
+	probes, err := tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(probes))
+	targets, err = tpClient.GetTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(targets))
+	requests := server.Requests()
+	assert.Equal(t, 2, len(requests))
+	for _, request := range requests {
+		assert.NotEmpty(t, request.Header.Get("If-None-Match"), request.Path)
+	}
+	_, err = tpClient.GetTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(server.Requests()))
+
+	// A modified listing is received again
+	now = now.Add(2 * time.Minute)
+	server.AddProbe("Prometheus", "Guest OS Processes")
+	probes, err = tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(probes))
+
+	tpClient.cache.Clear()
+	_, err = tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, server.RequestCount("GET", "/probe"))
+}
+
+func TestResponseCache_NewProbe(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetResponseCacheTTL(time.Hour).Create())
+	tpClient, _ := turboClient.TopologyProcessorClient()
+	probes, err := tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(probes))
+
+	// A probe registered after the probes were cached is found
+	server.RegisterProbeInstance(server.AddProbe("Prometheus", "Guest OS Processes"), "")
+	connected, err := tpClient.IsProbeConnected("Prometheus", "Guest OS Processes")
+	assert.NoError(t, err)
+	assert.True(t, connected)
+	server.AddProbe("AppDynamics", "Applications and Databases")
+	target := newTestTarget("AppDynamics", "appd", "p")
+	target.Category = "Applications and Databases"
+	outcome, err := turboClient.AddTarget(target, TopologyProcessor)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetCreated, outcome)
+	server.AddProbe("Dynatrace", "Applications and Databases")
+	target = newTestTarget("Dynatrace", "dynatrace", "p")
+	target.Category = "Applications and Databases"
+	report, err := turboClient.AddTargets(context.Background(), []*api.Target{target},
+		&AddTargetsOptions{Service: TopologyProcessor})
+	assert.NoError(t, err)
+	assert.NoError(t, report.Err())
+
+	// The probes found are cached
+	server.ResetRequests()
+	probes, err = tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(probes))
+	assert.Equal(t, 0, server.RequestCount("GET", "/probe"))
+}
+
+func TestResponseCache_Templates(t *testing.T) {
+	count := 0
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/templates":
+			count++
+			json.NewEncoder(w).Encode([]api.Template{{UUID: "1", DisplayName: "small"}})
+		case r.Method == "DELETE" && r.URL.Path == "/templates/1":
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "templates")
+	defer server.Close()
+	apiClient.ResponseCache(NewResponseCache(time.Minute))
+	templateClient := NewTemplateClient(apiClient)
+
+	for i := 0; i < 2; i++ {
+		templates, err := templateClient.ListTemplates()
+		assert.NoError(t, err)
+		assert.Equal(t, []api.Template{{UUID: "1", DisplayName: "small"}}, templates)
+	}
+	assert.Equal(t, 1, count)
+	// Without an ETag, an invalidated listing is received again
+	assert.NoError(t, templateClient.DeleteTemplate("1"))
+	_, err := templateClient.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestResponseCache_Invalidate(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	result := Result{statusCode: 200, status: "200 OK", header: http.Header{"Etag": {`"1"`}}, body: "[]"}
+
+	lookup := cache.lookup("/targets", "targets")
+	assert.Nil(t, lookup.entry)
+	cache.store(lookup, result)
+	lookup = cache.lookup("/targets", "targets")
+	assert.True(t, lookup.fresh)
+	assert.Equal(t, result, lookup.entry.result)
+	assert.Equal(t, `"1"`, lookup.entry.etag)
+
+	// A listing received while its group is written is not cached
+	cache.Invalidate(api.Resource_Type_Target)
+	lookup = cache.lookup("/targets", "targets")
+	assert.Nil(t, lookup.entry)
+	cache.Invalidate(api.Resource_Type_Targets)
+	cache.store(lookup, result)
+	assert.Nil(t, cache.lookup("/targets", "targets").entry)
+
+	// The other groups are not invalidated
+	cache.store(cache.lookup("/probe", "probes"), result)
+	cache.Invalidate(api.Resource_Type_Targets)
+	cache.Invalidate(api.Resource_Type_Actions)
+	assert.NotNil(t, cache.lookup("/probe", "probes").entry)
+}
@@ -1,12 +1,17 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"strings"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/golang/glog"
 	"github.com/turbonomic/turbo-api/pkg/api"
 )
 
@@ -20,138 +25,498 @@ var (
 	HydraPath             = "/oauth2/"
 	AuthPath              = "/vmturbo/auth/"
 
-	defaultRESTAPIEndpoints = map[string]string{
-		API:               APIPath,
-		TopologyProcessor: TopologyProcessorPath,
-		HYDRA:             HydraPath,
-		AUTH:              AuthPath,
+	// The endpoints of the services registered by default, on the server address
+	defaultServiceEndpoints = map[string]ServiceEndpoint{
+		API:               {Path: APIPath},
+		TopologyProcessor: {Path: TopologyProcessorPath, Unauthenticated: true},
+		HYDRA:             {Path: HydraPath},
+		AUTH:              {Path: AuthPath},
 	}
+
+	// The maximum time to wait for topology processor to respond when checking whether it is reachable
+	tpReachabilityTimeout = 5 * time.Second
 )
 
+// inCluster returns true if the client runs in a Kubernetes cluster
+var inCluster = func() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// ServiceEndpoint locates the REST API of a Turbonomic service
+type ServiceEndpoint struct {
+	// Base URL of the service, i.e. https://turbo.example.com, defaults to the server address
+	BaseURL *url.URL
+	// Path of the REST API on the base URL, i.e. /vmturbo/rest/
+	Path string
+	// Whether the service is called without logging in, like topology processor
+	Unauthenticated bool
+}
+
 type Client interface {
-	AddTarget(target *api.Target) error
-	DiscoverTarget(uuid string) (*Result, error)
+	AddTarget(target *api.Target) (TargetOutcome, error)
+	DiscoverTarget(uuid string) (*DiscoveryStatus, error)
+	GetDiscoveryStatus(uuid string) (*DiscoveryStatus, error)
+	WaitForDiscovery(ctx context.Context, uuid string, opts *DiscoveryWaitOptions) (*DiscoveryStatus, error)
 	GetHydraAccessToken() (string, error)
 	GetJwtToken(hydraToken string) (string, error)
 }
 
-// TurboClient manages REST clients to Turbonomic services
+// TurboClient manages REST clients to Turbonomic services.
+// It is safe for concurrent use by multiple goroutines.
 type TurboClient struct {
+	lock    sync.RWMutex
 	clients map[string]Client // A map that maps service name to REST client
+	// Check of the targets before they are added, if any
+	preflightCheck TargetPreflightCheck
+	// versionLock guards the cached version of the server and serializes its retrieval
+	versionLock sync.Mutex
+	version     *ServerVersion
 }
 
 func NewTurboClient(c *Config) (*TurboClient, error) {
 	// Build httpClient, which can be shared by multiple connections
+	endpoints := c.serviceEndpoints()
+	secure := false
+	for _, endpoint := range endpoints {
+		secure = secure || endpoint.BaseURL.Scheme == "https"
+	}
 	httpClient := http.DefaultClient
-	proxy := c.proxy
-	if proxy != "" || c.serverAddress.Scheme == "https" {
-		var tr http.Transport
-		if c.serverAddress.Scheme == "https" {
-			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if c.proxy != "" || c.noProxy != "" || secure {
+		proxy, err := newProxyFunc(c.proxy, c.noProxy)
+		if err != nil {
+			return nil, err
 		}
-
-		if proxy != "" {
-			//Check if the proxy server requires authentication or not
-			//Authenticated proxy format: http://username:password@ip:port
-			//Non-Aunthenticated proxy format: http://ip:port
-			if strings.Index(proxy, "@") != -1 {
-				//Extract the username password portion, with @
-				usernamePassword := proxy[strings.Index(proxy, "//")+2 : strings.LastIndex(proxy, "@")+1]
-				username := usernamePassword[:strings.Index(usernamePassword, ":")]
-				password := usernamePassword[strings.Index(usernamePassword, ":")+1 : strings.LastIndex(usernamePassword, "@")]
-				//Extract Proxy address by remove the username_password
-				proxyAddr := strings.ReplaceAll(proxy, usernamePassword, "")
-				proxyURL, err := url.Parse(proxyAddr)
-				if err != nil {
-					return nil, fmt.Errorf("Failed to parse proxy\n")
-				}
-				proxyURL.User = url.UserPassword(username, password)
-				tr.Proxy = http.ProxyURL(proxyURL)
-			} else {
-				proxyURL, err := url.Parse(proxy)
-				if err != nil {
-					return nil, fmt.Errorf("Failed to parse proxy\n")
-				}
-				tr.Proxy = http.ProxyURL(proxyURL)
-			}
+		tr := &http.Transport{Proxy: proxy}
+		if secure {
+			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 		}
-		httpClient = &http.Client{Transport: &tr}
+		httpClient = &http.Client{Transport: tr}
 	}
 	// Build the map of clients
 	turboClient := &TurboClient{
-		clients: make(map[string]Client),
+		clients:        make(map[string]Client),
+		preflightCheck: c.preflightCheck,
 	}
-	for service, endpoint := range defaultRESTAPIEndpoints {
-		turboClient.clients[service] = newClient(httpClient, c.serverAddress,
-			c.basicAuth, service, endpoint, c.clientId, c.clientSecret)
+	// The listings are cached once for all the services, so that the writes via a service
+	// invalidate the listings of all the services
+	var cache *ResponseCache
+	if c.responseCacheTTL > 0 {
+		cache = NewResponseCache(c.responseCacheTTL)
+	}
+	for service, endpoint := range endpoints {
+		turboClient.clients[service] = newClient(httpClient, c, service, endpoint, cache)
 	}
 	return turboClient, nil
 }
 
-func newClient(client *http.Client, baseURL *url.URL, basicAuth *BasicAuthentication,
-	service, endpoint, clientId, clientSecret string) Client {
-	restClient := NewRESTClient(client, baseURL, endpoint).BasicAuthentication(basicAuth)
+func newClient(client *http.Client, c *Config, service string, endpoint ServiceEndpoint, cache *ResponseCache) Client {
+	restClient := NewRESTClient(client, endpoint.BaseURL, endpoint.Path).
+		BasicAuthentication(c.basicAuth).
+		MaxResponseSize(c.maxResponseSize).
+		ResponseCache(cache)
+	if limit, found := c.rateLimits[service]; found {
+		restClient.RateLimiter(NewRateLimiter(limit))
+	}
 	if service == TopologyProcessor {
 		// Create a Turbo client without authentication
 		return &TPClient{
-			restClient,
+			RESTClient:           restClient,
+			TargetMatcher:        c.targetMatcher,
+			RecreateConfirmation: c.recreateConfirmation,
 		}
 	}
-	// Create a Turbo client based on basic authentication
+	// Create a Turbo client based on basic authentication, unless the service is called without logging in
 	return &APIClient{
-		restClient.BasicAuthentication(basicAuth),
-		nil, clientId, clientSecret,
+		RESTClient:           restClient,
+		ClientId:             c.clientId,
+		ClientSecret:         c.clientSecret,
+		TargetMatcher:        c.targetMatcher,
+		RecreateConfirmation: c.recreateConfirmation,
+		SkipMaskedSecrets:    c.skipMaskedSecrets,
+		Unauthenticated:      endpoint.Unauthenticated,
+	}
+}
+
+// RegisterClient registers the client of a service, replacing the client registered for the service if any
+func (turboClient *TurboClient) RegisterClient(service string, client Client) {
+	turboClient.lock.Lock()
+	defer turboClient.lock.Unlock()
+	turboClient.clients[service] = client
+}
+
+// getClient returns the client registered for the given service
+func (turboClient *TurboClient) getClient(service string) (Client, error) {
+	turboClient.lock.RLock()
+	defer turboClient.lock.RUnlock()
+	client, ok := turboClient.clients[service]
+	if !ok {
+		return nil, fmt.Errorf("client for service %v is not registered", service)
+	}
+	return client, nil
+}
+
+// TopologyProcessorClient returns the client of the topology processor service,
+// which can be used to inspect registered probes and the status of targets.
+func (turboClient *TurboClient) TopologyProcessorClient() (*TPClient, error) {
+	client, err := turboClient.getClient(TopologyProcessor)
+	if err != nil {
+		return nil, err
+	}
+	tpClient, ok := client.(*TPClient)
+	if !ok {
+		return nil, fmt.Errorf("client for service %v is not a topology processor client", TopologyProcessor)
+	}
+	return tpClient, nil
+}
+
+// apiClient returns the client of the api service
+func (turboClient *TurboClient) apiClient() (*APIClient, error) {
+	client, err := turboClient.getClient(API)
+	if err != nil {
+		return nil, err
+	}
+	apiClient, ok := client.(*APIClient)
+	if !ok {
+		return nil, fmt.Errorf("client for service %v is not an api client", API)
+	}
+	return apiClient, nil
+}
+
+// PlanClient returns a client running what-if plans via api service
+func (turboClient *TurboClient) PlanClient() (*PlanClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewPlanClient(apiClient), nil
+}
+
+// TemplateClient returns a client managing templates via api service
+func (turboClient *TurboClient) TemplateClient() (*TemplateClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplateClient(apiClient), nil
+}
+
+// StatsClient returns a client querying statistics via api service
+func (turboClient *TurboClient) StatsClient() (*StatsClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsClient(apiClient), nil
+}
+
+// SearchClient returns a client searching entities via api service
+func (turboClient *TurboClient) SearchClient() (*SearchClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewSearchClient(apiClient), nil
+}
+
+// MarketClient returns a client reading markets via api service
+func (turboClient *TurboClient) MarketClient() (*MarketClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewMarketClient(apiClient), nil
+}
+
+// UserClient returns a client managing users via api service
+func (turboClient *TurboClient) UserClient() (*UserClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewUserClient(apiClient), nil
+}
+
+// LicenseClient returns a client managing licenses via api service
+func (turboClient *TurboClient) LicenseClient() (*LicenseClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewLicenseClient(apiClient), nil
+}
+
+// TagClient returns a client managing tags via api service
+func (turboClient *TurboClient) TagClient() (*TagClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewTagClient(apiClient), nil
+}
+
+// ActionClient returns a client executing actions via api service
+func (turboClient *TurboClient) ActionClient() (*ActionClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewActionClient(apiClient), nil
+}
+
+// WorkflowClient returns a client managing workflows via api service
+func (turboClient *TurboClient) WorkflowClient() (*WorkflowClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewWorkflowClient(apiClient), nil
+}
+
+// NotificationClient returns a client subscribing to notifications via api service
+func (turboClient *TurboClient) NotificationClient() (*NotificationClient, error) {
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
 	}
+	return NewNotificationClient(apiClient), nil
+}
+
+// JwtTokenSource returns a function getting a JWT from auth service in exchange for an access token
+// from Hydra service, i.e. to authenticate notification subscriptions with SubscriptionOptions.JwtToken
+func (turboClient *TurboClient) JwtTokenSource() func() (string, error) {
+	return func() (string, error) {
+		hydraToken, err := turboClient.GetHydraAccessToken()
+		if err != nil {
+			return "", err
+		}
+		return turboClient.GetJwtToken(hydraToken)
+	}
+}
+
+// ServerVersion gets the version of the server via api service, which is cached after it is first retrieved
+func (turboClient *TurboClient) ServerVersion() (*ServerVersion, error) {
+	turboClient.versionLock.Lock()
+	defer turboClient.versionLock.Unlock()
+	if turboClient.version != nil {
+		return turboClient.version, nil
+	}
+	apiClient, err := turboClient.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	version, err := apiClient.GetServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	turboClient.version = version
+	return version, nil
+}
+
+// TargetService returns the service to add targets via, according to the version of the server:
+// topology processor if the server supports it and its client is registered, api service otherwise
+func (turboClient *TurboClient) TargetService() (string, error) {
+	version, err := turboClient.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	if version.SupportsTopologyProcessorTargets() {
+		if _, err := turboClient.TopologyProcessorClient(); err == nil {
+			return TopologyProcessor, nil
+		}
+	}
+	return API, nil
 }
 
 // GetHydraAccessToken gets the access token from Hydra service
 func (turboClient *TurboClient) GetHydraAccessToken() (string, error) {
-	client, ok := turboClient.clients[HYDRA]
-	if !ok {
-		return "", fmt.Errorf("client for service %v is not registered", HYDRA)
+	client, err := turboClient.getClient(HYDRA)
+	if err != nil {
+		return "", err
 	}
 	return client.GetHydraAccessToken()
 }
 
 // GetJwtToken gets the JwtToken from Hydra access token
 func (turboClient *TurboClient) GetJwtToken(hydraToken string) (string, error) {
-	client, ok := turboClient.clients[AUTH]
-	if !ok {
-		return "", fmt.Errorf("client for service %v is not registered", AUTH)
+	client, err := turboClient.getClient(AUTH)
+	if err != nil {
+		return "", err
 	}
 	return client.GetJwtToken(hydraToken)
 }
 
-// AddTarget adds a target via a given service
-func (turboClient *TurboClient) AddTarget(target *api.Target, service string) error {
-	client, ok := turboClient.clients[service]
-	if !ok {
-		return fmt.Errorf("client for service %v is not registered", service)
+// AddTarget adds a target via a given service, or updates it if it already exists,
+// and returns whether the target was created, updated, left unchanged or recreated
+func (turboClient *TurboClient) AddTarget(target *api.Target, service string) (TargetOutcome, error) {
+	client, err := turboClient.getClient(service)
+	if err != nil {
+		return "", err
+	}
+	if err := turboClient.checkTarget(target); err != nil {
+		return "", err
 	}
 	return client.AddTarget(target)
 }
 
-// AddTarget discovers a target via a given service
-func (turboClient *TurboClient) DiscoverTarget(uuid, service string) (*Result, error) {
-	client, ok := turboClient.clients[service]
-	if !ok {
-		return nil, fmt.Errorf("client for service %v is not registered", service)
+// checkTarget runs the preflight check of a target, if any
+func (turboClient *TurboClient) checkTarget(target *api.Target) error {
+	if turboClient.preflightCheck == nil {
+		return nil
+	}
+	if err := turboClient.preflightCheck(turboClient, target); err != nil {
+		return fmt.Errorf("preflight check of %s target %s failed: %v", target.Type, getTargetId(target), err)
+	}
+	return nil
+}
+
+// AddTargetResult reports how a target was added by AddTargetAuto
+type AddTargetResult struct {
+	// Service the target was added via, TopologyProcessor or API, empty if the target failed the preflight check
+	Service string
+	// The reason topology processor was not used, if the target was added via api service
+	FallbackReason string
+	// Whether the target was created, updated, left unchanged or recreated
+	Outcome TargetOutcome
+}
+
+// AddTargetAuto adds a target via topology processor service when the client runs in a Kubernetes cluster,
+// the server supports it, see TargetService, and topology processor is reachable, and falls back to api service
+// with authentication otherwise, or if topology processor fails to respond when adding the target.
+// The other errors of topology processor, i.e. a rejected target or a recreation which is not confirmed, are returned.
+// The returned result reports the service used, also when adding the target fails, but no service if the target
+// fails the preflight check.
+func (turboClient *TurboClient) AddTargetAuto(target *api.Target) (*AddTargetResult, error) {
+	result := &AddTargetResult{}
+	if err := turboClient.checkTarget(target); err != nil {
+		return result, err
+	}
+	if reason := turboClient.topologyProcessorUnavailable(); reason != "" {
+		result.Service = API
+		result.FallbackReason = reason
+	} else {
+		result.Service = TopologyProcessor
+		outcome, err := turboClient.addTarget(target, TopologyProcessor)
+		if err == nil || !unavailableError(err) {
+			result.Outcome = outcome
+			return result, err
+		}
+		result.Service = API
+		result.FallbackReason = fmt.Sprintf("adding the target via topology processor failed: %v", err)
+	}
+	glog.V(2).Infof("Adding %s target %s via api service because %s.", target.Type, getTargetId(target),
+		result.FallbackReason)
+	outcome, err := turboClient.addTarget(target, API)
+	result.Outcome = outcome
+	return result, err
+}
+
+// addTarget adds a target via a given service without checking it
+func (turboClient *TurboClient) addTarget(target *api.Target, service string) (TargetOutcome, error) {
+	client, err := turboClient.getClient(service)
+	if err != nil {
+		return "", err
+	}
+	return client.AddTarget(target)
+}
+
+// unavailableError returns true if an error shows that a service is unavailable, i.e. a transport error or a
+// transient unsuccessful response such as 503, rather than a failure which would recur via another service or
+// which followed changes to the existing target
+func unavailableError(err error) bool {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.Transient()
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// topologyProcessorUnavailable returns the reason targets cannot be added via topology processor service,
+// or an empty string if they can
+func (turboClient *TurboClient) topologyProcessorUnavailable() string {
+	if !inCluster() {
+		return "the client does not run in a Kubernetes cluster"
+	}
+	tpClient, err := turboClient.TopologyProcessorClient()
+	if err != nil {
+		return err.Error()
+	}
+	service, err := turboClient.TargetService()
+	if err != nil {
+		return fmt.Sprintf("failed to get the server version: %v", err)
+	}
+	if service != TopologyProcessor {
+		return "the server does not support adding targets via topology processor"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tpReachabilityTimeout)
+	defer cancel()
+	if err := tpClient.Ping(ctx); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// DiscoverTarget triggers the discovery of a target via a given service
+func (turboClient *TurboClient) DiscoverTarget(uuid, service string) (*DiscoveryStatus, error) {
+	client, err := turboClient.getClient(service)
+	if err != nil {
+		return nil, err
 	}
 	return client.DiscoverTarget(uuid)
 }
 
-// Get the target identifier for the given target
+// WaitForDiscovery waits for the validation and discovery of a target via a given service to settle.
+// A failed discovery is reported in the returned status, see DiscoveryStatus.Err.
+func (turboClient *TurboClient) WaitForDiscovery(ctx context.Context, uuid, service string,
+	opts *DiscoveryWaitOptions) (*DiscoveryStatus, error) {
+	client, err := turboClient.getClient(service)
+	if err != nil {
+		return nil, err
+	}
+	return client.WaitForDiscovery(ctx, uuid, opts)
+}
+
+// Get the target identifier for the given target: the value of its first identifying field set
 func getTargetId(target *api.Target) string {
-	for _, inputField := range target.InputFields {
-		field := inputField.Name
-		if field == "targetIdentifier" {
-			tgtId := inputField.Value
-			return tgtId
+	for _, names := range [][]string{target.IdentifyingFields, defaultIdentifyingFields} {
+		for _, name := range names {
+			if value := inputFieldValue(target, name); value != "" {
+				return value
+			}
 		}
 	}
 	return ""
 }
 
+// ResponseError is the error of an unsuccessful response of a service
+type ResponseError struct {
+	// Status code of the response, i.e. 404
+	StatusCode int
+	message    string
+}
+
+func (e *ResponseError) Error() string {
+	return e.message
+}
+
+// Transient returns true if the request may succeed when sent again, i.e. when the service is unavailable or
+// limits the rate of the requests
+func (e *ResponseError) Transient() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// permanentError returns true if an error is an unsuccessful response which would not succeed when the request is
+// sent again, i.e. a response with a status code 4xx other than 429. Transport errors are not permanent.
+func permanentError(err error) bool {
+	var responseErr *ResponseError
+	return errors.As(err, &responseErr) && !responseErr.Transient()
+}
+
+// buildStatusError builds the error of an unsuccessful response like buildResponseError, as a ResponseError
+func buildStatusError(requestDesc string, statusCode int, status string, content string) error {
+	return &ResponseError{StatusCode: statusCode, message: buildResponseError(requestDesc, status, content).Error()}
+}
+
 func buildResponseError(requestDesc string, status string, content string) error {
 	errorMsg := fmt.Sprintf("unsuccessful %s response: %s.", requestDesc, status)
 	errorDTO, err := parseAPIErrorDTO(content)
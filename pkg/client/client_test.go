@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
 )
 
 func TestNewTurboClient(t *testing.T) {
@@ -21,30 +25,29 @@ func TestNewTurboClient(t *testing.T) {
 		expectedClient Client
 	}{
 		{
-			config:  &Config{baseURL, &BasicAuthentication{"foo", "bar"}, "", "", ""},
+			config:  &Config{serverAddress: baseURL, basicAuth: &BasicAuthentication{"foo", "bar"}},
 			service: API,
 			expectedClient: &APIClient{
-				&RESTClient{http.DefaultClient, baseURL, APIPath, &BasicAuthentication{"foo", "bar"}},
-				nil, "", "",
+				RESTClient: &RESTClient{client: http.DefaultClient, baseURL: baseURL, apiPath: APIPath,
+					basicAuth: &BasicAuthentication{"foo", "bar"}},
 			},
 		},
 		{
-			config:  &Config{secureURL, &BasicAuthentication{"foo", "bar"}, "", "", ""},
+			config:  &Config{serverAddress: secureURL, basicAuth: &BasicAuthentication{"foo", "bar"}},
 			service: API,
 			expectedClient: &APIClient{
-				&RESTClient{&http.Client{Transport: &http.Transport{
+				RESTClient: &RESTClient{client: &http.Client{Transport: &http.Transport{
 					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				}}, secureURL, APIPath, &BasicAuthentication{"foo", "bar"}},
-				nil, "", "",
+				}}, baseURL: secureURL, apiPath: APIPath, basicAuth: &BasicAuthentication{"foo", "bar"}},
 			},
 		},
 		{
-			config:  &Config{secureURL, nil, "", "", ""},
+			config:  &Config{serverAddress: secureURL},
 			service: TopologyProcessor,
 			expectedClient: &TPClient{
-				&RESTClient{&http.Client{Transport: &http.Transport{
+				RESTClient: &RESTClient{client: &http.Client{Transport: &http.Transport{
 					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				}}, secureURL, TopologyProcessorPath, nil},
+				}}, baseURL: secureURL, apiPath: TopologyProcessorPath},
 			},
 		},
 	}
@@ -57,6 +60,20 @@ func TestNewTurboClient(t *testing.T) {
 		if !found {
 			t.Errorf("Client for service %v is not found", item.service)
 		}
+		// Proxy functions cannot be compared, the transports built for https use the environment proxy settings
+		var httpClient *http.Client
+		switch c := client.(type) {
+		case *APIClient:
+			httpClient = c.client
+		case *TPClient:
+			httpClient = c.client
+		}
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			if transport.Proxy == nil {
+				t.Errorf("Expected transport with proxy function for %v", item.config.serverAddress)
+			}
+			transport.Proxy = nil
+		}
 		if !reflect.DeepEqual(client, item.expectedClient) {
 			t.Errorf("Expected client %+v, got %+v", item.expectedClient, client)
 		}
@@ -67,7 +84,7 @@ func TestNewTurboClient(t *testing.T) {
 func TestClient_DiscoverTarget_WithError(t *testing.T) {
 	uuid := ""
 	baseURL, _ := url.Parse("http://localhost")
-	config := &Config{baseURL, &BasicAuthentication{"foo", "bar"}, "", "", ""}
+	config := &Config{serverAddress: baseURL, basicAuth: &BasicAuthentication{"foo", "bar"}}
 	turboClient, _ := NewTurboClient(config)
 	_, err := turboClient.DiscoverTarget(uuid, API)
 	if err == nil {
@@ -78,9 +95,9 @@ func TestClient_DiscoverTarget_WithError(t *testing.T) {
 func TestClient_AddTarget_WithError(t *testing.T) {
 	target := &api.Target{}
 	baseURL, _ := url.Parse("http://localhost")
-	config := &Config{baseURL, &BasicAuthentication{"foo", "bar"}, "", "", ""}
+	config := &Config{serverAddress: baseURL, basicAuth: &BasicAuthentication{"foo", "bar"}}
 	turboClient, _ := NewTurboClient(config)
-	if err := turboClient.AddTarget(target, API); err == nil {
+	if _, err := turboClient.AddTarget(target, API); err == nil {
 		t.Error("Expected error, but got no error.")
 	}
 }
@@ -115,3 +132,503 @@ func TestBuildErrorAPIDTO(t *testing.T) {
 		}
 	}
 }
+
+func TestTurboClient_TopologyProcessorClient(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost")
+	turboClient, _ := NewTurboClient(&Config{serverAddress: baseURL})
+	tpClient, err := turboClient.TopologyProcessorClient()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if tpClient != turboClient.clients[TopologyProcessor] {
+		t.Errorf("Expected client %+v, got %+v", turboClient.clients[TopologyProcessor], tpClient)
+	}
+
+	delete(turboClient.clients, TopologyProcessor)
+	if _, err := turboClient.TopologyProcessorClient(); err == nil {
+		t.Error("Expected error, but got no error.")
+	}
+}
+
+func newTestTurboClient(t *testing.T, server *fake.Server) *TurboClient {
+	config := NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").Create()
+	turboClient, err := NewTurboClient(config)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return turboClient
+}
+
+func withInputField(target *api.Target, name, value string) *api.Target {
+	target.InputFields = append(target.InputFields, &api.InputField{Name: name, Value: value})
+	return target
+}
+
+func newTestTarget(probeType, identifier, password string) *api.Target {
+	return &api.Target{
+		Category: "Cloud Native",
+		Type:     probeType,
+		InputFields: []*api.InputField{
+			{Name: "targetIdentifier", Value: identifier},
+			{Name: "password", Value: password, IsSecret: true},
+		},
+	}
+}
+
+func TestTurboClient_AddTarget(t *testing.T) {
+	table := []struct {
+		service    string
+		targetPath string
+	}{
+		{API, "/vmturbo/rest/targets"},
+		{TopologyProcessor, "/target"},
+	}
+	for _, item := range table {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("Kubernetes-old", "Cloud Native")
+		server.AddProbe("Kubernetes", "Cloud Native")
+		turboClient := newTestTurboClient(t, server)
+
+		// Create
+		outcome, err := turboClient.AddTarget(newTestTarget("Kubernetes-old", "cluster", "p1"), item.service)
+		assert.NoError(t, err, item.service)
+		assert.Equal(t, TargetCreated, outcome, item.service)
+		targets := server.Targets()
+		assert.Equal(t, 1, len(targets), item.service)
+		assert.Equal(t, "Kubernetes-old", targets[0].Type, item.service)
+
+		// Update, rotating the password
+		outcome, err = turboClient.AddTarget(newTestTarget("Kubernetes-old", "cluster", "p2"), item.service)
+		assert.NoError(t, err, item.service)
+		assert.Equal(t, TargetUpdated, outcome, item.service)
+		assert.Equal(t, 1, server.RequestCount("PUT", item.targetPath+"/"+targets[0].UUID), item.service)
+		assert.Equal(t, 1, len(server.Targets()), item.service)
+		assert.Contains(t, string(server.Requests()[len(server.Requests())-1].Body), `"p2"`, item.service)
+
+		// Unchanged, keeping the secret masked by api service
+		outcome, err = turboClient.AddTarget(newTestTarget("Kubernetes-old", "cluster", MaskedSecretValue), item.service)
+		assert.NoError(t, err, item.service)
+		assert.Equal(t, TargetUnchanged, outcome, item.service)
+		assert.Equal(t, 1, server.RequestCount("PUT", item.targetPath+"/"+targets[0].UUID), item.service)
+
+		// Recreate since the probe type has changed
+		outcome, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p2"), item.service)
+		assert.NoError(t, err, item.service)
+		assert.Equal(t, TargetRecreated, outcome, item.service)
+		assert.Equal(t, 1, server.RequestCount("DELETE", item.targetPath+"/"+targets[0].UUID), item.service)
+		targets = server.Targets()
+		assert.Equal(t, 1, len(targets), item.service)
+		assert.Equal(t, "Kubernetes", targets[0].Type, item.service)
+
+		// Server error
+		server.InjectFault("POST", item.targetPath, fake.Fault{StatusCode: 502, Body: `{"message":"down"}`})
+		_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "other", "p"), item.service)
+		assert.EqualError(t, err, "unsuccessful target addition response: 502 Bad Gateway. down.", item.service)
+		server.Close()
+	}
+}
+
+func TestTurboClient_AddTarget_MalformedResponse(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.InjectFault("GET", "/vmturbo/rest/targets", fake.Fault{MalformedJSON: true})
+	turboClient := newTestTurboClient(t, server)
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.Error(t, err)
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/targets"))
+}
+
+func TestTurboClient_AddTarget_LoginFailure(t *testing.T) {
+	server := fake.NewServer("foo", "other")
+	defer server.Close()
+	turboClient := newTestTurboClient(t, server)
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.Error(t, err)
+	assert.Equal(t, 1, server.RequestCount("POST", "/vmturbo/rest/login"))
+	assert.Equal(t, 0, server.RequestCount("", "/vmturbo/rest/targets"))
+}
+
+func TestNewTurboClient_ServiceEndpoints(t *testing.T) {
+	baseURL, _ := url.Parse("https://turbo.example.com")
+	tpURL, _ := url.Parse("http://topology-processor.turbonomic:8080")
+	turboClient, err := NewTurboClient(NewConfigBuilder(baseURL).
+		SetServiceEndpoint(TopologyProcessor, tpURL, "/tp/").
+		RegisterService("cost", ServiceEndpoint{Path: "/vmturbo/cost/"}).
+		RegisterService("extractor", ServiceEndpoint{Path: "/extractor/", Unauthenticated: true}).
+		Create())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	tpClient, err := turboClient.TopologyProcessorClient()
+	assert.NoError(t, err)
+	assert.Equal(t, tpURL, tpClient.baseURL)
+	assert.Equal(t, "/tp/", tpClient.apiPath)
+	apiClient, err := turboClient.apiClient()
+	assert.NoError(t, err)
+	assert.Equal(t, baseURL, apiClient.baseURL)
+	assert.Equal(t, APIPath, apiClient.apiPath)
+	// All the clients share the transport trusting the https services
+	assert.Same(t, apiClient.client, tpClient.client)
+	costClient, err := turboClient.getClient("cost")
+	assert.NoError(t, err)
+	assert.IsType(t, &APIClient{}, costClient)
+	assert.Equal(t, "/vmturbo/cost/", costClient.(*APIClient).apiPath)
+	assert.False(t, costClient.(*APIClient).Unauthenticated)
+	// A service without authentication other than topology processor does not get a topology processor client
+	extractorClient, err := turboClient.getClient("extractor")
+	assert.NoError(t, err)
+	if assert.IsType(t, &APIClient{}, extractorClient) {
+		assert.True(t, extractorClient.(*APIClient).Unauthenticated)
+	}
+
+	// The topology processor is reached through https even if the api service is not
+	baseURL, _ = url.Parse("http://turbo.example.com")
+	tpURL, _ = url.Parse("https://topology-processor.turbonomic")
+	turboClient, _ = NewTurboClient(NewConfigBuilder(baseURL).SetServiceEndpoint(TopologyProcessor, tpURL, "").Create())
+	tpClient, _ = turboClient.TopologyProcessorClient()
+	transport, ok := tpClient.client.Transport.(*http.Transport)
+	if assert.True(t, ok) {
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	}
+}
+
+func TestTurboClient_RegisterClient(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost")
+	turboClient, _ := NewTurboClient(&Config{serverAddress: baseURL})
+	_, err := turboClient.getClient("cost")
+	assert.EqualError(t, err, "client for service cost is not registered")
+	costClient := &APIClient{RESTClient: NewRESTClient(http.DefaultClient, baseURL, "/vmturbo/cost/")}
+	turboClient.RegisterClient("cost", costClient)
+	client, err := turboClient.getClient("cost")
+	assert.NoError(t, err)
+	assert.Same(t, costClient, client)
+}
+
+func TestTurboClient_AddTargetAuto(t *testing.T) {
+	defer func(f func() bool) { inCluster = f }(inCluster)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	closedURL, _ := url.Parse(closed.URL)
+	table := []struct {
+		inCluster      bool
+		tpFault        *fake.Fault
+		addFault       *fake.Fault
+		tpURL          *url.URL
+		productVersion *api.ProductVersion
+
+		expectedResult *AddTargetResult
+		targetPath     string
+	}{
+		{
+			// Out of cluster
+			inCluster: false,
+
+			expectedResult: &AddTargetResult{Service: API, Outcome: TargetCreated,
+				FallbackReason: "the client does not run in a Kubernetes cluster"},
+			targetPath: "/vmturbo/rest/targets",
+		},
+		{
+			// In cluster
+			inCluster: true,
+
+			expectedResult: &AddTargetResult{Service: TopologyProcessor, Outcome: TargetCreated},
+			targetPath:     "/target",
+		},
+		{
+			// Topology processor failing
+			inCluster: true,
+			tpFault:   &fake.Fault{StatusCode: http.StatusServiceUnavailable},
+
+			expectedResult: &AddTargetResult{Service: API, Outcome: TargetCreated,
+				FallbackReason: "unsuccessful topology processor ping response: 503 Service Unavailable."},
+			targetPath: "/vmturbo/rest/targets",
+		},
+		{
+			// Server not supporting topology processor
+			inCluster:      true,
+			productVersion: &api.ProductVersion{VersionInfo: "Turbonomic Operations Manager 6.4.10", MarketVersion: 1},
+
+			expectedResult: &AddTargetResult{Service: API, Outcome: TargetCreated,
+				FallbackReason: "the server does not support adding targets via topology processor"},
+			targetPath: "/vmturbo/rest/targets",
+		},
+		{
+			// Topology processor failing to add the target
+			inCluster: true,
+			addFault:  &fake.Fault{StatusCode: http.StatusInternalServerError},
+
+			expectedResult: &AddTargetResult{Service: API, Outcome: TargetCreated,
+				FallbackReason: "adding the target via topology processor failed: " +
+					"unsuccessful target addition response: 500 Internal Server Error."},
+			targetPath: "/vmturbo/rest/targets",
+		},
+		{
+			// Topology processor unreachable
+			inCluster: true,
+			tpURL:     closedURL,
+
+			expectedResult: &AddTargetResult{Service: API, Outcome: TargetCreated},
+			targetPath:     "/vmturbo/rest/targets",
+		},
+	}
+	for _, item := range table {
+		inCluster = func() bool { return item.inCluster }
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("Kubernetes", "Cloud Native")
+		if item.tpFault != nil {
+			server.InjectFault("GET", "/probe", *item.tpFault)
+		}
+		if item.addFault != nil {
+			server.InjectFault("POST", "/target", *item.addFault)
+		}
+		if item.productVersion != nil {
+			server.SetProductVersion(item.productVersion)
+		}
+		cb := NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar")
+		if item.tpURL != nil {
+			cb.SetServiceEndpoint(TopologyProcessor, item.tpURL, "")
+		}
+		turboClient, _ := NewTurboClient(cb.Create())
+
+		result, err := turboClient.AddTargetAuto(newTestTarget("Kubernetes", "cluster", "p"))
+		assert.NoError(t, err)
+		if item.tpURL != nil {
+			assert.Contains(t, result.FallbackReason, "topology processor is not reachable")
+			result.FallbackReason = ""
+		}
+		assert.Equal(t, item.expectedResult, result)
+		assert.Equal(t, 1, server.RequestCount("POST", item.targetPath))
+		assert.Equal(t, 1, len(server.Targets()))
+		server.Close()
+	}
+}
+
+func TestTurboClient_AddTargetAuto_Errors(t *testing.T) {
+	defer func(f func() bool) { inCluster = f }(inCluster)
+	inCluster = func() bool { return true }
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes-old", "Cloud Native")
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetAllowRecreate(false).Create())
+
+	// A target rejected by topology processor is not added via api service
+	server.InjectFault("POST", "/target", fake.Fault{StatusCode: http.StatusBadRequest, Body: `{"message":"invalid"}`,
+		Times: 1})
+	result, err := turboClient.AddTargetAuto(newTestTarget("Kubernetes-old", "cluster", "p"))
+	assert.EqualError(t, err, "unsuccessful target addition response: 400 Bad Request. invalid.")
+	assert.Equal(t, &AddTargetResult{Service: TopologyProcessor}, result)
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/targets"))
+
+	// Neither is a target whose recreation is not confirmed
+	_, err = turboClient.AddTargetAuto(newTestTarget("Kubernetes-old", "cluster", "p"))
+	assert.NoError(t, err)
+	result, err = turboClient.AddTargetAuto(newTestTarget("Kubernetes", "cluster", "p"))
+	assert.True(t, errors.Is(err, ErrRecreateNotConfirmed), "%v", err)
+	assert.Equal(t, TopologyProcessor, result.Service)
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/targets"))
+
+	// Nor a target failing to be added after the existing one was deleted
+	turboClient, _ = NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").Create())
+	server.InjectFault("POST", "/target", fake.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+	result, err = turboClient.AddTargetAuto(newTestTarget("Kubernetes", "cluster", "p"))
+	assert.Error(t, err)
+	assert.Equal(t, TopologyProcessor, result.Service)
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/targets"))
+	assert.Equal(t, "Kubernetes-old", server.Targets()[0].Type)
+
+	// No service is used for a target failing the preflight check
+	turboClient, _ = NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetTargetPreflightCheck(func(*TurboClient, *api.Target) error { return errors.New("not licensed") }).Create())
+	result, err = turboClient.AddTargetAuto(newTestTarget("Kubernetes", "other", "p"))
+	assert.EqualError(t, err, "preflight check of Kubernetes target other failed: not licensed")
+	assert.Equal(t, &AddTargetResult{}, result)
+}
+
+func TestTurboClient_AddTarget_RecreateMaskedSecrets(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes-old", "Cloud Native")
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient := newTestTurboClient(t, server)
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes-old", "cluster", "p1"), API)
+	assert.NoError(t, err)
+	uuid := server.Targets()[0].UUID
+
+	// The old target is not deleted when its secret masked by api service could not be restored
+	desired := newTestTarget("Kubernetes", "cluster", "t1")
+	desired.InputFields[1].Name = "token"
+	_, err = turboClient.AddTarget(desired, API)
+	assert.True(t, errors.Is(err, ErrSecretsNotRestorable), "%v", err)
+	assert.Equal(t, 0, server.RequestCount("DELETE", "/vmturbo/rest/targets/"+uuid))
+	targets := server.Targets()
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, "Kubernetes-old", targets[0].Type)
+
+	// Topology processor does not mask the secrets
+	outcome, err := turboClient.AddTarget(desired, TopologyProcessor)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetRecreated, outcome)
+}
+
+func TestTurboClient_AddTarget_IdentifyingFields(t *testing.T) {
+	for _, service := range []string{API, TopologyProcessor} {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("vCenter", "Hypervisor", "nameOrAddress")
+		server.AddProbe("AWS", "Cloud Management", "address", "username")
+		turboClient := newTestTurboClient(t, server)
+
+		// vCenter targets are identified by name or address
+		vCenter := newIdentifiedTarget("Hypervisor", "vCenter", nil, "nameOrAddress", "vc", "username", "a")
+		outcome, err := turboClient.AddTarget(vCenter, service)
+		assert.NoError(t, err, service)
+		assert.Equal(t, TargetCreated, outcome, service)
+		outcome, err = turboClient.AddTarget(vCenter, service)
+		assert.NoError(t, err, service)
+		assert.Equal(t, TargetUnchanged, outcome, service)
+
+		// AWS targets are identified by address and username, as reported by the probe
+		aws := newIdentifiedTarget("Cloud Management", "AWS", nil, "address", "aws", "username", "key1")
+		_, err = turboClient.AddTarget(aws, service)
+		assert.NoError(t, err, service)
+		aws = newIdentifiedTarget("Cloud Management", "AWS", nil, "address", "aws", "username", "key2")
+		outcome, err = turboClient.AddTarget(aws, service)
+		assert.NoError(t, err, service)
+		assert.Equal(t, TargetCreated, outcome, service)
+		assert.Equal(t, 3, len(server.Targets()), service)
+		server.Close()
+	}
+}
+
+func TestTurboClient_AddTarget_SkipMaskedSecrets(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetSkipMaskedSecrets(true).Create())
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p1"), API)
+	assert.NoError(t, err)
+	uuid := server.Targets()[0].UUID
+
+	// The password masked by api service may have changed, but is not updated
+	outcome, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p2"), API)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetSecretsUnverified, outcome)
+	outcome, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", MaskedSecretValue), API)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetUnchanged, outcome)
+	assert.Equal(t, 0, server.RequestCount("PUT", "/vmturbo/rest/targets/"+uuid))
+	// The other fields are updated with the password
+	outcome, err = turboClient.AddTarget(
+		withInputField(newTestTarget("Kubernetes", "cluster", "p2"), "proxy", "proxy:3128"), API)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetUpdated, outcome)
+	assert.Equal(t, 1, server.RequestCount("PUT", "/vmturbo/rest/targets/"+uuid))
+}
+
+func TestTurboClient_AddTarget_OtherProbeType(t *testing.T) {
+	for _, service := range []string{API, TopologyProcessor} {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("SQLServer", "Database Server")
+		server.AddProbe("MySQL", "Database Server")
+		turboClient := newTestTurboClient(t, server)
+
+		// A target of another probe on the same address is left alone
+		_, err := turboClient.AddTarget(
+			newIdentifiedTarget("Database Server", "SQLServer", nil, "nameOrAddress", "db", "username", "sa"), service)
+		assert.NoError(t, err, service)
+		outcome, err := turboClient.AddTarget(
+			newIdentifiedTarget("Database Server", "MySQL", nil, "nameOrAddress", "db", "username", "root"), service)
+		assert.NoError(t, err, service)
+		assert.Equal(t, TargetCreated, outcome, service)
+		targets := server.Targets()
+		assert.Equal(t, 2, len(targets), service)
+		assert.Equal(t, 0, server.RequestCount("DELETE", "/vmturbo/rest/targets/"+targets[0].UUID), service)
+		assert.Equal(t, 0, server.RequestCount("DELETE", "/target/"+targets[0].UUID), service)
+		server.Close()
+	}
+}
+
+func TestTurboClient_AddTarget_TargetMatcher(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("vCenter", "Hypervisor")
+	// Match vCenter targets by address regardless of its case
+	matcher := func(existing, desired *api.Target) bool {
+		return strings.EqualFold(inputFieldValue(existing, "nameOrAddress"), inputFieldValue(desired, "nameOrAddress"))
+	}
+	for _, service := range []string{API, TopologyProcessor} {
+		turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+			SetTargetMatcher(matcher).Create())
+		outcome, err := turboClient.AddTarget(
+			newIdentifiedTarget("Hypervisor", "vCenter", nil, "nameOrAddress", "VC", "username", service), service)
+		assert.NoError(t, err, service)
+		if service == API {
+			assert.Equal(t, TargetCreated, outcome, service)
+		} else {
+			assert.Equal(t, TargetUpdated, outcome, service)
+		}
+		targets := server.Targets()
+		assert.Equal(t, 1, len(targets), service)
+		_, err = turboClient.AddTarget(
+			newIdentifiedTarget("Hypervisor", "vCenter", nil, "nameOrAddress", "vc", "username", service), service)
+		assert.NoError(t, err, service)
+		assert.Equal(t, 1, len(server.Targets()), service)
+	}
+}
+
+func TestTurboClient_AddTarget_Recreate(t *testing.T) {
+	table := []struct {
+		service    string
+		targetPath string
+	}{
+		{API, "/vmturbo/rest/targets"},
+		{TopologyProcessor, "/target"},
+	}
+	for _, item := range table {
+		server := fake.NewServer("foo", "bar")
+		server.AddProbe("Kubernetes-old", "Cloud Native")
+		server.AddProbe("Kubernetes", "Cloud Native")
+		var confirmed []string
+		turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+			SetRecreateConfirmation(func(existing, desired *api.Target) bool {
+				confirmed = append(confirmed, existing.Type+" "+desired.Type)
+				return desired.DisplayName != "refused"
+			}).Create())
+		_, err := turboClient.AddTarget(newTestTarget("Kubernetes-old", "cluster", "p1"), item.service)
+		assert.NoError(t, err, item.service)
+
+		// The recreation is refused
+		refused := newTestTarget("Kubernetes", "cluster", "p1")
+		refused.DisplayName = "refused"
+		_, err = turboClient.AddTarget(refused, item.service)
+		assert.True(t, errors.Is(err, ErrRecreateNotConfirmed), item.service)
+		assert.Equal(t, []string{"Kubernetes-old Kubernetes"}, confirmed, item.service)
+		assert.Equal(t, 0, server.RequestCount("DELETE", item.targetPath+"/"+server.Targets()[0].UUID), item.service)
+		assert.Equal(t, 1, len(server.Targets()), item.service)
+
+		// The old target is restored when the new one fails to be added
+		server.InjectFault("POST", item.targetPath, fake.Fault{StatusCode: 400, Body: `{"message":"invalid"}`, Times: 1})
+		_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p1"), item.service)
+		assert.EqualError(t, err, "failed to add target cluster of type Kubernetes after deleting it with type "+
+			"Kubernetes-old: unsuccessful target addition response: 400 Bad Request. invalid.; restored the deleted target",
+			item.service)
+		targets := server.Targets()
+		assert.Equal(t, 1, len(targets), item.service)
+		assert.Equal(t, "Kubernetes-old", targets[0].Type, item.service)
+		assert.Equal(t, []*api.InputField{
+			{Name: "targetIdentifier", Value: "cluster"},
+			{Name: "password", Value: "*****", IsSecret: true},
+		}, targets[0].InputFields, item.service)
+
+		// The old target is lost when it fails to be restored
+		server.InjectFault("POST", item.targetPath, fake.Fault{StatusCode: 502, Times: 2})
+		_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p1"), item.service)
+		assert.Error(t, err, item.service)
+		assert.Contains(t, err.Error(), "failed to restore the deleted target: unsuccessful target addition response: "+
+			"502 Bad Gateway.", item.service)
+		assert.Equal(t, 0, len(server.Targets()), item.service)
+		server.Close()
+	}
+}
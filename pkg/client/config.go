@@ -2,6 +2,7 @@ package client
 
 import (
 	"net/url"
+	"time"
 )
 
 type Config struct {
@@ -9,18 +10,45 @@ type Config struct {
 	serverAddress *url.URL
 	// For Basic authentication.
 	basicAuth *BasicAuthentication
-	// For proxy
-	proxy        string
+	// For proxy, [scheme://][username:password@]host:port
+	proxy string
+	// Comma-separated list of hosts bypassing the proxy
+	noProxy      string
 	clientId     string
 	clientSecret string
+	// The maximum size of a buffered response body, 0 means no limit.
+	maxResponseSize int64
+	// Check of the targets before they are added, if any
+	preflightCheck TargetPreflightCheck
+	// Endpoints of the services overriding or adding to the default ones
+	endpoints map[string]ServiceEndpoint
+	// Matcher of existing targets with the added ones, if not matching by identifying fields
+	targetMatcher TargetMatcher
+	// Confirmation of the recreation of existing targets of another probe type, if required
+	recreateConfirmation RecreateConfirmation
+	// Whether existing targets are left untouched via api service when only their masked secret fields may differ
+	skipMaskedSecrets bool
+	// The time the listings of probes, targets and templates are cached for, 0 means no cache
+	responseCacheTTL time.Duration
+	// Limits of the rate of the requests to the services, if any
+	rateLimits map[string]RateLimit
 }
 
 type ConfigBuilder struct {
-	serverAddress *url.URL
-	basicAuth     *BasicAuthentication
-	proxy         string
-	clientId      string
-	clientSecret  string
+	serverAddress        *url.URL
+	basicAuth            *BasicAuthentication
+	proxy                string
+	noProxy              string
+	clientId             string
+	clientSecret         string
+	maxResponseSize      int64
+	preflightCheck       TargetPreflightCheck
+	endpoints            map[string]ServiceEndpoint
+	targetMatcher        TargetMatcher
+	recreateConfirmation RecreateConfirmation
+	skipMaskedSecrets    bool
+	responseCacheTTL     time.Duration
+	rateLimits           map[string]RateLimit
 }
 
 func NewConfigBuilder(serverAddress *url.URL) *ConfigBuilder {
@@ -29,11 +57,21 @@ func NewConfigBuilder(serverAddress *url.URL) *ConfigBuilder {
 	}
 }
 
+// SetProxy sets the proxy to Turbonomic services, of the form [scheme://][username:password@]host:port.
+// The scheme is http, https or socks5 and defaults to http. Without a proxy, the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables apply.
 func (cb *ConfigBuilder) SetProxy(proxy string) *ConfigBuilder {
 	cb.proxy = proxy
 	return cb
 }
 
+// SetNoProxy sets the comma-separated list of hosts, domains, IP addresses and CIDR blocks bypassing the proxy,
+// in the format of the NO_PROXY environment variable, which it defaults to
+func (cb *ConfigBuilder) SetNoProxy(noProxy string) *ConfigBuilder {
+	cb.noProxy = noProxy
+	return cb
+}
+
 func (cb *ConfigBuilder) SetClientId(clientId string) *ConfigBuilder {
 	cb.clientId = clientId
 	return cb
@@ -44,6 +82,91 @@ func (cb *ConfigBuilder) SetClientSecret(clientSecret string) *ConfigBuilder {
 	return cb
 }
 
+// SetMaxResponseSize limits the number of bytes read into memory from a response body.
+// Requests exceeding the limit fail instead of exhausting memory; use Request.Stream for large payloads.
+func (cb *ConfigBuilder) SetMaxResponseSize(size int64) *ConfigBuilder {
+	cb.maxResponseSize = size
+	return cb
+}
+
+// SetTargetPreflightCheck sets a check run before adding each target, i.e. LicensePreflightCheck
+func (cb *ConfigBuilder) SetTargetPreflightCheck(check TargetPreflightCheck) *ConfigBuilder {
+	cb.preflightCheck = check
+	return cb
+}
+
+// SetServiceEndpoint sets the base URL and the path of the REST API of a service, i.e. to reach topology processor
+// through its in-cluster service while api service is reached through ingress. A nil base URL defaults to the server
+// address and an empty path to the path of the service. A service which is not registered yet is added as a service
+// authenticated like api service, see RegisterService to add a service without authentication.
+func (cb *ConfigBuilder) SetServiceEndpoint(service string, baseURL *url.URL, path string) *ConfigBuilder {
+	endpoint, found := cb.endpoints[service]
+	if !found {
+		endpoint = defaultServiceEndpoints[service]
+	}
+	endpoint.BaseURL = baseURL
+	if path != "" {
+		endpoint.Path = path
+	}
+	return cb.RegisterService(service, endpoint)
+}
+
+// RegisterService registers a service in addition to the default ones, or replaces the endpoint of a default service
+func (cb *ConfigBuilder) RegisterService(service string, endpoint ServiceEndpoint) *ConfigBuilder {
+	if cb.endpoints == nil {
+		cb.endpoints = make(map[string]ServiceEndpoint)
+	}
+	cb.endpoints[service] = endpoint
+	return cb
+}
+
+// SetTargetMatcher sets the matcher finding the existing target to update when adding a target,
+// for custom identity rules; targets are matched with MatchByIdentifyingFields by default
+func (cb *ConfigBuilder) SetTargetMatcher(matcher TargetMatcher) *ConfigBuilder {
+	cb.targetMatcher = matcher
+	return cb
+}
+
+// SetRecreateConfirmation sets the confirmation required before deleting an existing target to add it again with
+// another probe type. Existing targets are recreated without confirmation by default.
+func (cb *ConfigBuilder) SetRecreateConfirmation(confirm RecreateConfirmation) *ConfigBuilder {
+	cb.recreateConfirmation = confirm
+	return cb
+}
+
+// SetAllowRecreate sets whether existing targets can be deleted to be added again with another probe type
+func (cb *ConfigBuilder) SetAllowRecreate(allow bool) *ConfigBuilder {
+	return cb.SetRecreateConfirmation(AllowRecreate(allow))
+}
+
+// SetSkipMaskedSecrets sets whether the existing targets are left untouched when they are added via api service with
+// secret fields, i.e. passwords, which api service masks, and their other fields are unchanged. Such targets are
+// updated by default, since a changed secret cannot be detected, and are rediscovered every time they are added.
+// When skipped, they are reported as TargetSecretsUnverified.
+func (cb *ConfigBuilder) SetSkipMaskedSecrets(skip bool) *ConfigBuilder {
+	cb.skipMaskedSecrets = skip
+	return cb
+}
+
+// SetResponseCacheTTL caches the listings of probes, targets and templates for the given time, after which they are
+// revalidated with their ETag, if any. The listings are invalidated by the writes of the client, but not by the ones
+// of other clients of the server. The listings are not cached if the TTL is 0, which is the default.
+func (cb *ConfigBuilder) SetResponseCacheTTL(ttl time.Duration) *ConfigBuilder {
+	cb.responseCacheTTL = ttl
+	return cb
+}
+
+// SetRateLimit limits the rate of the requests to a service, which wait or fail fast with ErrRateLimited when
+// exceeding it. The requests to a service with a rate limit are also held for the time given by the Retry-After header
+// of the 429 and 503 responses of the service. The requests are not limited by default.
+func (cb *ConfigBuilder) SetRateLimit(service string, limit RateLimit) *ConfigBuilder {
+	if cb.rateLimits == nil {
+		cb.rateLimits = make(map[string]RateLimit)
+	}
+	cb.rateLimits[service] = limit
+	return cb
+}
+
 func (cb *ConfigBuilder) BasicAuthentication(usrn, passd string) *ConfigBuilder {
 	cb.basicAuth = &BasicAuthentication{
 		username: usrn,
@@ -53,11 +176,52 @@ func (cb *ConfigBuilder) BasicAuthentication(usrn, passd string) *ConfigBuilder
 }
 
 func (cb *ConfigBuilder) Create() *Config {
+	var endpoints map[string]ServiceEndpoint
+	if len(cb.endpoints) > 0 {
+		endpoints = make(map[string]ServiceEndpoint, len(cb.endpoints))
+		for service, endpoint := range cb.endpoints {
+			endpoints[service] = endpoint
+		}
+	}
+	var rateLimits map[string]RateLimit
+	if len(cb.rateLimits) > 0 {
+		rateLimits = make(map[string]RateLimit, len(cb.rateLimits))
+		for service, limit := range cb.rateLimits {
+			rateLimits[service] = limit
+		}
+	}
 	return &Config{
-		serverAddress: cb.serverAddress,
-		basicAuth:     cb.basicAuth,
-		proxy:         cb.proxy,
-		clientId:      cb.clientId,
-		clientSecret:  cb.clientSecret,
+		serverAddress:        cb.serverAddress,
+		basicAuth:            cb.basicAuth,
+		proxy:                cb.proxy,
+		noProxy:              cb.noProxy,
+		clientId:             cb.clientId,
+		clientSecret:         cb.clientSecret,
+		maxResponseSize:      cb.maxResponseSize,
+		preflightCheck:       cb.preflightCheck,
+		endpoints:            endpoints,
+		targetMatcher:        cb.targetMatcher,
+		recreateConfirmation: cb.recreateConfirmation,
+		skipMaskedSecrets:    cb.skipMaskedSecrets,
+		responseCacheTTL:     cb.responseCacheTTL,
+		rateLimits:           rateLimits,
+	}
+}
+
+// serviceEndpoints returns the endpoints of all the services, with their base URL defaulting to the server address
+func (c *Config) serviceEndpoints() map[string]ServiceEndpoint {
+	endpoints := make(map[string]ServiceEndpoint, len(defaultServiceEndpoints)+len(c.endpoints))
+	for service, endpoint := range defaultServiceEndpoints {
+		endpoints[service] = endpoint
+	}
+	for service, endpoint := range c.endpoints {
+		endpoints[service] = endpoint
+	}
+	for service, endpoint := range endpoints {
+		if endpoint.BaseURL == nil {
+			endpoint.BaseURL = c.serverAddress
+			endpoints[service] = endpoint
+		}
 	}
+	return endpoints
 }
@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewConfigBuilder(t *testing.T) {
@@ -54,20 +55,32 @@ func TestConfigBuilder_BasicAuthentication(t *testing.T) {
 func TestConfigBuilder_Create(t *testing.T) {
 	baseURL, _ := url.Parse("http://localhost")
 	table := []struct {
-		serverAddress  *url.URL
-		username       string
-		password       string
-		expectedConfig *Config
+		serverAddress   *url.URL
+		username        string
+		password        string
+		maxResponseSize int64
+		cacheTTL        time.Duration
+		expectedConfig  *Config
 	}{
 		{
 			serverAddress:  baseURL,
 			username:       "foo",
 			password:       "bar",
-			expectedConfig: &Config{baseURL, &BasicAuthentication{"foo", "bar"}, "", "", ""},
+			expectedConfig: &Config{serverAddress: baseURL, basicAuth: &BasicAuthentication{"foo", "bar"}},
 		},
 		{
 			serverAddress:  baseURL,
-			expectedConfig: &Config{baseURL, nil, "", "", ""},
+			expectedConfig: &Config{serverAddress: baseURL},
+		},
+		{
+			serverAddress:   baseURL,
+			maxResponseSize: 1024,
+			expectedConfig:  &Config{serverAddress: baseURL, maxResponseSize: 1024},
+		},
+		{
+			serverAddress:  baseURL,
+			cacheTTL:       time.Minute,
+			expectedConfig: &Config{serverAddress: baseURL, responseCacheTTL: time.Minute},
 		},
 	}
 	for _, item := range table {
@@ -75,6 +88,12 @@ func TestConfigBuilder_Create(t *testing.T) {
 		if item.username != "" && item.password != "" {
 			cb = cb.BasicAuthentication(item.username, item.password)
 		}
+		if item.maxResponseSize != 0 {
+			cb = cb.SetMaxResponseSize(item.maxResponseSize)
+		}
+		if item.cacheTTL != 0 {
+			cb = cb.SetResponseCacheTTL(item.cacheTTL)
+		}
 		config := cb.Create()
 		if !reflect.DeepEqual(item.expectedConfig, config) {
 			t.Errorf("Expect config %++v, got %++v",
@@ -82,3 +101,45 @@ func TestConfigBuilder_Create(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigBuilder_SetServiceEndpoint(t *testing.T) {
+	baseURL, _ := url.Parse("https://turbo.example.com")
+	tpURL, _ := url.Parse("http://topology-processor.turbonomic:8080")
+	extractorURL, _ := url.Parse("http://extractor.turbonomic:8080")
+	config := NewConfigBuilder(baseURL).
+		SetServiceEndpoint(TopologyProcessor, tpURL, "").
+		SetServiceEndpoint(API, nil, "/api/v3/").
+		SetServiceEndpoint("cost", nil, "/vmturbo/cost/").
+		RegisterService("extractor", ServiceEndpoint{BaseURL: extractorURL, Path: "/", Unauthenticated: true}).
+		Create()
+	expected := map[string]ServiceEndpoint{
+		API:               {BaseURL: baseURL, Path: "/api/v3/"},
+		TopologyProcessor: {BaseURL: tpURL, Path: TopologyProcessorPath, Unauthenticated: true},
+		HYDRA:             {BaseURL: baseURL, Path: HydraPath},
+		AUTH:              {BaseURL: baseURL, Path: AuthPath},
+		"cost":            {BaseURL: baseURL, Path: "/vmturbo/cost/"},
+		"extractor":       {BaseURL: extractorURL, Path: "/", Unauthenticated: true},
+	}
+	if endpoints := config.serviceEndpoints(); !reflect.DeepEqual(expected, endpoints) {
+		t.Errorf("Expect service endpoints %++v, got %++v", expected, endpoints)
+	}
+	if endpoints := NewConfigBuilder(baseURL).Create().serviceEndpoints(); len(endpoints) != len(defaultServiceEndpoints) {
+		t.Errorf("Expect %d default service endpoints, got %++v", len(defaultServiceEndpoints), endpoints)
+	}
+}
+
+func TestConfigBuilder_SetRateLimit(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost")
+	cb := NewConfigBuilder(baseURL).
+		SetRateLimit(API, RateLimit{Rate: 1, Burst: 5}).
+		SetRateLimit(TopologyProcessor, RateLimit{Rate: 10, FailFast: true})
+	config := cb.Create()
+	cb.SetRateLimit(API, RateLimit{Rate: 2})
+	expected := map[string]RateLimit{
+		API:               {Rate: 1, Burst: 5},
+		TopologyProcessor: {Rate: 10, FailFast: true},
+	}
+	if !reflect.DeepEqual(expected, config.rateLimits) {
+		t.Errorf("Expect rate limits %++v, got %++v", expected, config.rateLimits)
+	}
+}
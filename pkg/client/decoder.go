@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONArrayDecoder decodes the elements of a JSON array one at a time from a stream,
+// so that large listings never have to be held in memory as a whole.
+//
+// The array can be either the top level value of the stream, e.g. [{...}, {...}],
+// or the value of a field of the top level object, e.g. {"targets": [{...}, {...}]}.
+type JSONArrayDecoder struct {
+	decoder *json.Decoder
+	// Name of the top level field holding the array, empty if the array is the top level value
+	field   string
+	started bool
+	err     error
+}
+
+// NewJSONArrayDecoder creates a decoder for a stream whose top level value is a JSON array.
+func NewJSONArrayDecoder(r io.Reader) *JSONArrayDecoder {
+	return &JSONArrayDecoder{
+		decoder: json.NewDecoder(r),
+	}
+}
+
+// NewJSONArrayFieldDecoder creates a decoder for a stream whose top level value is a JSON object
+// holding the array in the given field.
+func NewJSONArrayFieldDecoder(r io.Reader, field string) *JSONArrayDecoder {
+	return &JSONArrayDecoder{
+		decoder: json.NewDecoder(r),
+		field:   field,
+	}
+}
+
+// More reports whether there is another element in the array.
+// It returns false at the end of the array or if an error occurred, in which case Err returns the error.
+func (d *JSONArrayDecoder) More() bool {
+	if d.err != nil {
+		return false
+	}
+	if !d.started {
+		if d.err = d.start(); d.err != nil {
+			return false
+		}
+	}
+	if d.decoder.More() {
+		return true
+	}
+	// Consume the closing bracket of the array
+	if _, err := d.decoder.Token(); err != nil {
+		d.err = fmt.Errorf("failed to read end of array: %v", err)
+	}
+	return false
+}
+
+// Decode decodes the next element of the array into the value pointed to by v.
+func (d *JSONArrayDecoder) Decode(v interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+	if !d.started {
+		if d.err = d.start(); d.err != nil {
+			return d.err
+		}
+	}
+	if err := d.decoder.Decode(v); err != nil {
+		d.err = fmt.Errorf("failed to decode array element: %v", err)
+		return d.err
+	}
+	return nil
+}
+
+// Err returns the first error encountered while decoding, if any.
+func (d *JSONArrayDecoder) Err() error {
+	return d.err
+}
+
+// start positions the decoder right after the opening bracket of the array.
+func (d *JSONArrayDecoder) start() error {
+	d.started = true
+	if d.field != "" {
+		if err := d.expectDelim('{'); err != nil {
+			return err
+		}
+		if err := d.seekField(); err != nil {
+			return err
+		}
+	}
+	return d.expectDelim('[')
+}
+
+// seekField skips the fields of the top level object until the array field is found.
+func (d *JSONArrayDecoder) seekField() error {
+	for d.decoder.More() {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read field name: %v", err)
+		}
+		if name, ok := token.(string); ok && name == d.field {
+			return nil
+		}
+		var skipped json.RawMessage
+		if err := d.decoder.Decode(&skipped); err != nil {
+			return fmt.Errorf("failed to skip field %v: %v", token, err)
+		}
+	}
+	return fmt.Errorf("failed to find key %q from response", d.field)
+}
+
+func (d *JSONArrayDecoder) expectDelim(expected json.Delim) error {
+	token, err := d.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON token: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %v in JSON stream, got %v", expected, token)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestJSONArrayDecoder(t *testing.T) {
+	table := []struct {
+		input         string
+		field         string
+		expectedNames []string
+		expectsError  bool
+	}{
+		{
+			input:         `[{"displayName":"foo"},{"displayName":"bar"}]`,
+			expectedNames: []string{"foo", "bar"},
+		},
+		{
+			input: `[]`,
+		},
+		{
+			input:         `{"count":2,"targets":[{"displayName":"foo"},{"displayName":"bar"}],"next":{"a":[1]}}`,
+			field:         "targets",
+			expectedNames: []string{"foo", "bar"},
+		},
+		{
+			input:        `{"probes":[{"displayName":"foo"}]}`,
+			field:        "targets",
+			expectsError: true,
+		},
+		{
+			input:        `{"displayName":"foo"}`,
+			expectsError: true,
+		},
+		{
+			input:         `[{"displayName":"foo"},{"displayName":`,
+			expectedNames: []string{"foo"},
+			expectsError:  true,
+		},
+	}
+
+	for _, item := range table {
+		var decoder *JSONArrayDecoder
+		if item.field == "" {
+			decoder = NewJSONArrayDecoder(strings.NewReader(item.input))
+		} else {
+			decoder = NewJSONArrayFieldDecoder(strings.NewReader(item.input), item.field)
+		}
+		var names []string
+		for decoder.More() {
+			var target api.Target
+			if err := decoder.Decode(&target); err != nil {
+				break
+			}
+			names = append(names, target.DisplayName)
+		}
+		if item.expectsError != (decoder.Err() != nil) {
+			t.Errorf("Input %s: expects error %v, got %v", item.input, item.expectsError, decoder.Err())
+		}
+		if !reflect.DeepEqual(item.expectedNames, names) {
+			t.Errorf("Input %s: expected %v, got %v", item.input, item.expectedNames, names)
+		}
+	}
+}
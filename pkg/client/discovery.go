@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// DiscoveryState is the state of the validation and discovery of a target
+type DiscoveryState string
+
+const (
+	DiscoveryInProgress DiscoveryState = "InProgress"
+	DiscoverySucceeded  DiscoveryState = "Succeeded"
+	DiscoveryFailed     DiscoveryState = "Failed"
+)
+
+var (
+	defaultDiscoveryPollInterval = 5 * time.Second
+	defaultDiscoveryTimeout      = 10 * time.Minute
+)
+
+// DiscoveryStatus is the validation and discovery status of a target
+type DiscoveryStatus struct {
+	// UUID of the target in the service that reported the status
+	TargetID string
+	State    DiscoveryState
+	// Status description reported by the server, which holds the error details when the discovery failed
+	Status string
+	// Time of the last validation or discovery as reported by the server
+	LastValidated string
+	// Time of the validation or discovery preceding the one triggered by DiscoverTarget, only set by DiscoverTarget
+	PreviousValidated string
+}
+
+// DiscoveryWaitOptions configures how WaitForDiscovery polls the status of a target
+type DiscoveryWaitOptions struct {
+	// Interval between two polls of the target status, defaults to 5 seconds
+	PollInterval time.Duration
+	// Maximum time to wait for the discovery to settle, defaults to 10 minutes
+	Timeout time.Duration
+	// The status returned by DiscoverTarget, if waiting for the triggered discovery. The settled status of the
+	// preceding discovery, which the target keeps until the triggered discovery starts, is then not accepted.
+	Since *DiscoveryStatus
+}
+
+func newDiscoveryStatus(targetID, status, lastValidated string) *DiscoveryStatus {
+	return &DiscoveryStatus{
+		TargetID:      targetID,
+		State:         parseDiscoveryState(status),
+		Status:        status,
+		LastValidated: lastValidated,
+	}
+}
+
+// parseDiscoveryState maps the status description of a target to a discovery state.
+// Any status other than validated or in progress describes a failure.
+func parseDiscoveryState(status string) DiscoveryState {
+	normalized := strings.ToLower(strings.TrimSpace(status))
+	switch {
+	case normalized == "" ||
+		normalized == "not validated" ||
+		strings.Contains(normalized, "in progress"):
+		return DiscoveryInProgress
+	case normalized == "validated":
+		return DiscoverySucceeded
+	default:
+		return DiscoveryFailed
+	}
+}
+
+// Settled returns true if the discovery is no longer in progress
+func (s *DiscoveryStatus) Settled() bool {
+	return s.State != DiscoveryInProgress
+}
+
+// Err returns an error with the failure details if the discovery failed
+func (s *DiscoveryStatus) Err() error {
+	if s.State != DiscoveryFailed {
+		return nil
+	}
+	return fmt.Errorf("discovery of target %s failed: %s", s.TargetID, s.Status)
+}
+
+// waitForDiscovery polls the status of a target until the validation and discovery settles,
+// the context is done or the timeout expires.
+// The last polled status is returned together with the error if the discovery does not settle.
+// Getting the status is retried on transport errors and transient unsuccessful responses, i.e. 5xx, but not on
+// the other unsuccessful responses such as 404 for a target which does not exist.
+func waitForDiscovery(ctx context.Context, targetID string, opts *DiscoveryWaitOptions,
+	getStatus func(targetID string) (*DiscoveryStatus, error)) (*DiscoveryStatus, error) {
+	pollInterval, timeout, previousValidated := defaultDiscoveryPollInterval, defaultDiscoveryTimeout, ""
+	if opts != nil {
+		if opts.PollInterval > 0 {
+			pollInterval = opts.PollInterval
+		}
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+		if opts.Since != nil {
+			previousValidated = opts.Since.PreviousValidated
+		}
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var lastStatus *DiscoveryStatus
+	for {
+		status, err := getStatus(targetID)
+		if permanentError(err) {
+			// The target does not exist or the client is not allowed to get it, which polling does not change
+			return lastStatus, fmt.Errorf("failed to wait for discovery of target %s: %w", targetID, err)
+		}
+		if err != nil {
+			glog.Warningf("Failed to get discovery status of target %s: %v", targetID, err)
+		} else {
+			glog.V(4).Infof("Discovery status of target %s: %+v", targetID, status)
+			// The status of the preceding discovery is kept until the triggered one starts
+			if status.Settled() && (previousValidated == "" || status.LastValidated != previousValidated) {
+				return status, nil
+			}
+			lastStatus = status
+		}
+		select {
+		case <-waitCtx.Done():
+			var waitErr error
+			if ctx.Err() != nil {
+				waitErr = fmt.Errorf("stopped waiting for discovery of target %s: %w", targetID, ctx.Err())
+			} else {
+				waitErr = fmt.Errorf("timed out after %v waiting for discovery of target %s", timeout, targetID)
+			}
+			if err != nil {
+				return lastStatus, fmt.Errorf("%w, failed to get its status: %v", waitErr, err)
+			}
+			return lastStatus, waitErr
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiscoveryState(t *testing.T) {
+	table := []struct {
+		status        string
+		expectedState DiscoveryState
+	}{
+		{"Validated", DiscoverySucceeded},
+		{"", DiscoveryInProgress},
+		{"Not Validated", DiscoveryInProgress},
+		{"Validation in progress", DiscoveryInProgress},
+		{"Discovery in progress", DiscoveryInProgress},
+		{"Validation failed: connection refused", DiscoveryFailed},
+		{"Unable to connect to the server", DiscoveryFailed},
+	}
+	for _, item := range table {
+		if state := parseDiscoveryState(item.status); state != item.expectedState {
+			t.Errorf("Status %q: expected state %v, got %v", item.status, item.expectedState, state)
+		}
+	}
+}
+
+func TestDiscoveryStatus_Err(t *testing.T) {
+	assert.NoError(t, newDiscoveryStatus("1", "Validated", "").Err())
+	assert.NoError(t, newDiscoveryStatus("1", "Validation in progress", "").Err())
+	assert.EqualError(t, newDiscoveryStatus("1", "Unable to connect", "").Err(),
+		"discovery of target 1 failed: Unable to connect")
+}
+
+func TestWaitForDiscovery(t *testing.T) {
+	table := []struct {
+		statuses          []*DiscoveryStatus
+		errs              []error
+		previousValidated string
+		expectedStatus    *DiscoveryStatus
+		expectsError      bool
+	}{
+		{
+			statuses: []*DiscoveryStatus{
+				newDiscoveryStatus("1", "Validation in progress", ""),
+				newDiscoveryStatus("1", "Discovery in progress", ""),
+				newDiscoveryStatus("1", "Validated", "t1"),
+			},
+			expectedStatus: newDiscoveryStatus("1", "Validated", "t1"),
+		},
+		{
+			statuses: []*DiscoveryStatus{
+				newDiscoveryStatus("1", "", ""),
+				newDiscoveryStatus("1", "Validation failed: bad credentials", "t1"),
+			},
+			errs:           []error{errors.New("connection refused")},
+			expectedStatus: newDiscoveryStatus("1", "Validation failed: bad credentials", "t1"),
+		},
+		{
+			statuses:       []*DiscoveryStatus{newDiscoveryStatus("1", "Validation in progress", "")},
+			expectedStatus: newDiscoveryStatus("1", "Validation in progress", ""),
+			expectsError:   true,
+		},
+		{
+			// The status of the preceding discovery is not the one of the triggered discovery
+			statuses: []*DiscoveryStatus{
+				newDiscoveryStatus("1", "Validated", "t1"),
+				newDiscoveryStatus("1", "Discovery in progress", "t1"),
+				newDiscoveryStatus("1", "Validation failed: bad credentials", "t2"),
+			},
+			previousValidated: "t1",
+			expectedStatus:    newDiscoveryStatus("1", "Validation failed: bad credentials", "t2"),
+		},
+		{
+			statuses:          []*DiscoveryStatus{newDiscoveryStatus("1", "Validated", "t1")},
+			previousValidated: "t1",
+			expectedStatus:    newDiscoveryStatus("1", "Validated", "t1"),
+			expectsError:      true,
+		},
+	}
+	for _, item := range table {
+		polls := 0
+		getStatus := func(targetID string) (*DiscoveryStatus, error) {
+			defer func() { polls++ }()
+			if polls < len(item.errs) && item.errs[polls] != nil {
+				return nil, item.errs[polls]
+			}
+			i := polls
+			if i >= len(item.statuses) {
+				i = len(item.statuses) - 1
+			}
+			return item.statuses[i], nil
+		}
+		opts := &DiscoveryWaitOptions{PollInterval: time.Millisecond, Timeout: 100 * time.Millisecond,
+			Since: &DiscoveryStatus{PreviousValidated: item.previousValidated}}
+		status, err := waitForDiscovery(context.Background(), "1", opts, getStatus)
+		if item.expectsError != (err != nil) {
+			t.Errorf("Statuses %v: expects error %v, got %v", item.statuses, item.expectsError, err)
+		}
+		if !reflect.DeepEqual(item.expectedStatus, status) {
+			t.Errorf("Statuses %v: expected status %+v, got %+v", item.statuses, item.expectedStatus, status)
+		}
+	}
+}
+
+func TestWaitForDiscovery_Errors(t *testing.T) {
+	inProgress := func(targetID string) (*DiscoveryStatus, error) {
+		return newDiscoveryStatus(targetID, "Discovery in progress", ""), nil
+	}
+	_, err := waitForDiscovery(context.Background(), "1",
+		&DiscoveryWaitOptions{PollInterval: time.Millisecond, Timeout: 10 * time.Millisecond}, inProgress)
+	assert.EqualError(t, err, "timed out after 10ms waiting for discovery of target 1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = waitForDiscovery(ctx, "1", nil, inProgress)
+	assert.EqualError(t, err, "stopped waiting for discovery of target 1: context canceled")
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = waitForDiscovery(ctx, "1", nil, func(targetID string) (*DiscoveryStatus, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "stopped waiting for discovery of target 1: context canceled, "+
+		"failed to get its status: connection refused")
+
+	// The unsuccessful responses other than 5xx and 429 are not retried
+	table := []struct {
+		statusCode int
+
+		expectedPolls int
+	}{
+		{statusCode: http.StatusNotFound, expectedPolls: 1},
+		{statusCode: http.StatusUnauthorized, expectedPolls: 1},
+		{statusCode: http.StatusTooManyRequests, expectedPolls: 3},
+		{statusCode: http.StatusBadGateway, expectedPolls: 3},
+	}
+	for _, item := range table {
+		polls := 0
+		_, err = waitForDiscovery(context.Background(), "1", &DiscoveryWaitOptions{PollInterval: time.Millisecond},
+			func(targetID string) (*DiscoveryStatus, error) {
+				polls++
+				if polls == 3 {
+					return newDiscoveryStatus(targetID, "Validated", ""), nil
+				}
+				return nil, buildStatusError("get target", item.statusCode, http.StatusText(item.statusCode), "")
+			})
+		assert.Equal(t, item.expectedPolls, polls, item.statusCode)
+		assert.Equal(t, item.expectedPolls == 1, err != nil, item.statusCode)
+	}
+}
+
+func TestTPClient_DiscoverTarget(t *testing.T) {
+	polls := 0
+	tpClient, server := newTestTPClient(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/target/1/rediscover":
+			fmt.Fprint(w, `{"targetId":"1"}`)
+		case r.Method == "GET" && r.URL.Path == "/target/1":
+			// The status of the preceding discovery is kept until the triggered discovery starts
+			polls++
+			switch {
+			case polls < 3:
+				fmt.Fprint(w, `{"targetId":"1","status":"Validated","lastValidationTime":"2020-01-01T00:00:00Z"}`)
+			case polls < 5:
+				fmt.Fprint(w, `{"targetId":"1","status":"Discovery in progress"}`)
+			default:
+				fmt.Fprint(w, `{"targetId":"1","status":"Validated","lastValidationTime":"2020-01-02T00:00:00Z"}`)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	status, err := tpClient.DiscoverTarget("1")
+	assert.NoError(t, err)
+	assert.Equal(t, DiscoverySucceeded, status.State)
+	assert.Equal(t, "2020-01-01T00:00:00Z", status.PreviousValidated)
+
+	status, err = tpClient.WaitForDiscovery(context.Background(), "1",
+		&DiscoveryWaitOptions{PollInterval: time.Millisecond, Since: status})
+	assert.NoError(t, err)
+	assert.Equal(t, DiscoverySucceeded, status.State)
+	assert.Equal(t, "2020-01-02T00:00:00Z", status.LastValidated)
+
+	_, err = tpClient.DiscoverTarget("foo")
+	assert.Error(t, err)
+	_, err = tpClient.WaitForDiscovery(context.Background(), "foo", nil)
+	assert.Error(t, err)
+	// A target which does not exist is not waited for
+	_, err = tpClient.WaitForDiscovery(context.Background(), "2", nil)
+	assert.EqualError(t, err, "failed to wait for discovery of target 2: unsuccessful get target response: 404 Not Found.")
+}
+
+func TestAPIClient_DiscoverTarget(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/targets/foo":
+			fmt.Fprint(w, `{"uuid":"foo","type":"vCenter","status":"Validated","lastValidated":"2020-01-01T00:00:00Z"}`)
+		case r.Method == "POST" && r.URL.Path == "/targets/foo" && r.URL.Query().Get("rediscover") == "true":
+			fmt.Fprint(w, `{"uuid":"foo","type":"vCenter","status":"Validation failed: bad credentials",`+
+				`"lastValidated":"2020-01-02T00:00:00Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "targets")
+	defer server.Close()
+
+	status, err := apiClient.DiscoverTarget("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, DiscoveryFailed, status.State)
+	assert.Equal(t, "2020-01-01T00:00:00Z", status.PreviousValidated)
+	assert.EqualError(t, status.Err(), "discovery of target foo failed: Validation failed: bad credentials")
+
+	_, err = apiClient.DiscoverTarget("bar")
+	assert.EqualError(t, err, "failed to discover target bar: unsuccessful get target response: 404 Not Found.")
+}
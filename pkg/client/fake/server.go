@@ -0,0 +1,793 @@
+// Package fake provides an in-memory stand-in for a Turbonomic server, so that consumers of the
+// client package can be tested without a real Turbonomic instance.
+//
+// The server implements the login, Hydra token and auth token exchange endpoints, the target
+// endpoints of the api service and the target and probe endpoints of the topology processor
+// service. Targets are shared between the two services, like they are on a real server.
+// Faults such as latency, error status codes and malformed JSON can be injected per endpoint,
+// and all the requests received are recorded for assertions. The listings of targets and probes are
+// served with an ETag, and are not sent again to requests with a matching If-None-Match header.
+// The other resources of the api service and the WebSocket endpoint of the notifications can be served
+// by handlers registered with HandleAPI and HandleNotifications.
+package fake
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+const (
+	// SessionCookie is the name of the cookie holding the session issued by the login endpoint
+	SessionCookie = "JSESSIONID"
+
+	apiPath  = "/vmturbo/rest/"
+	authPath = "/vmturbo/auth/"
+	// Path of the WebSocket endpoint of the notifications
+	notificationPath = "/vmturbo/messages"
+	tpPath           = "/"
+	// Prefix of the paths of the Hydra service
+	hydraPath = "/oauth2/"
+
+	// DefaultVersionInfo is the version info served by the api service until SetProductVersion is called,
+	// which is the one of an XL server supporting Hydra and topology processor targets
+	DefaultVersionInfo = `Turbonomic Operations Manager 8.14.3 (Build "20240110100300000") "2024-01-10 10:03:00"`
+
+	// ValidatedStatus is the status of a target which has been successfully validated and discovered
+	ValidatedStatus = "Validated"
+)
+
+// Fault describes an error injected in the responses of an endpoint
+type Fault struct {
+	// Delay before the response is sent
+	Latency time.Duration
+	// Status code of the response, e.g. 401 or 502; the request is handled normally if 0
+	StatusCode int
+	// Body of the response sent with StatusCode
+	Body string
+	// Headers of the response sent with StatusCode, e.g. Retry-After
+	Header http.Header
+	// Respond with a successful status and a malformed JSON body
+	MalformedJSON bool
+	// Number of requests the fault applies to, 0 means all requests until the faults are cleared
+	Times int
+}
+
+// RecordedRequest is a request received by the server
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+type faultRule struct {
+	method string
+	path   string
+	fault  Fault
+	hits   int
+}
+
+type target struct {
+	id                          int64
+	probe                       *api.ProbeDescription
+	displayName                 string
+	inputFields                 []*api.InputField
+	communicationBindingChannel string
+	status                      string
+	lastValidated               string
+}
+
+// Server is an in-memory Turbonomic server
+type Server struct {
+	server *httptest.Server
+
+	lock            sync.Mutex
+	username        string
+	password        string
+	clientID        string
+	clientSecret    string
+	sessions        map[string]bool
+	hydraTokens     map[string]bool
+	nextID          int64
+	probes          []*api.ProbeDescription
+	registrations   []api.ProbeRegistration
+	targets         map[int64]*target
+	discoveryStatus string
+	licenseSummary  *api.LicenseSummary
+	productVersion  *api.ProductVersion
+	faults          []*faultRule
+	requests        []RecordedRequest
+	apiHandlers     map[string]http.HandlerFunc
+	notifications   http.HandlerFunc
+}
+
+// NewServer starts a fake Turbonomic server accepting the given username and password for login.
+// The caller should call Close when finished, to shut it down.
+func NewServer(username, password string) *Server {
+	s := &Server{
+		username:        username,
+		password:        password,
+		sessions:        make(map[string]bool),
+		hydraTokens:     make(map[string]bool),
+		nextID:          1000,
+		targets:         make(map[int64]*target),
+		apiHandlers:     make(map[string]http.HandlerFunc),
+		discoveryStatus: ValidatedStatus,
+		productVersion: &api.ProductVersion{
+			VersionInfo:   DefaultVersionInfo,
+			MarketVersion: api.MarketVersionXL,
+		},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL of the server
+func (s *Server) URL() *url.URL {
+	u, _ := url.Parse(s.server.URL)
+	return u
+}
+
+// Client returns an HTTP client configured for making requests to the server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetClientCredentials sets the client id and secret accepted by the Hydra token endpoint
+func (s *Server) SetClientCredentials(clientID, clientSecret string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clientID = clientID
+	s.clientSecret = clientSecret
+}
+
+// AddProbe registers a probe type and returns its ID
+func (s *Server) AddProbe(probeType, probeCategory string, identifyingFields ...string) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	probe := &api.ProbeDescription{
+		ID:                s.newID(),
+		Type:              probeType,
+		Category:          probeCategory,
+		IdentifyingFields: identifyingFields,
+	}
+	s.probes = append(s.probes, probe)
+	return probe.ID
+}
+
+// RegisterProbeInstance registers a connected instance of the probe with the given ID
+func (s *Server) RegisterProbeInstance(probeID int64, communicationBindingChannel string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.registrations = append(s.registrations, api.ProbeRegistration{
+		ID:                          s.newID(),
+		ProbeID:                     probeID,
+		CommunicationBindingChannel: communicationBindingChannel,
+		RegisteredTime:              time.Now().UnixNano() / int64(time.Millisecond),
+		HealthState:                 "NORMAL",
+		Status:                      "Connected",
+	})
+}
+
+// SetDiscoveryStatus sets the status given to targets when they are added or rediscovered
+func (s *Server) SetDiscoveryStatus(status string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.discoveryStatus = status
+}
+
+// SetLicenseSummary sets the summary of the licenses served by the api service; licenses are not found until set
+func (s *Server) SetLicenseSummary(summary *api.LicenseSummary) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.licenseSummary = summary
+}
+
+// SetProductVersion sets the product version served by the api service
+func (s *Server) SetProductVersion(version *api.ProductVersion) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.productVersion = version
+}
+
+// SetTargetStatus sets the status of the target with the given UUID
+func (s *Server) SetTargetStatus(uuid, status string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tgt, err := s.findTargetByUUID(uuid)
+	if err != nil {
+		return err
+	}
+	tgt.status = status
+	tgt.lastValidated = time.Now().UTC().Format(time.RFC3339Nano)
+	return nil
+}
+
+// Targets returns the targets of the server as seen by the api service
+func (s *Server) Targets() []api.Target {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.apiTargets()
+}
+
+// ExpireSessions invalidates all the sessions issued by the login endpoint
+func (s *Server) ExpireSessions() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+// InjectFault injects a fault in the responses to the requests with the given method and path.
+// An empty method matches any method. Faults are applied in the order they are injected.
+func (s *Server) InjectFault(method, path string, fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = append(s.faults, &faultRule{method: method, path: path, fault: fault})
+}
+
+// ClearFaults removes all the injected faults
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+// HandleAPI serves the requests to the given resource of the api service, e.g. "actions", with the given
+// handler instead of the server. Like the other requests, they are recorded, are subject to the injected faults
+// and need a session. The handler receives them with a path relative to the api service path, e.g. "/actions/1".
+func (s *Server) HandleAPI(resource string, handler http.HandlerFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.apiHandlers[resource] = handler
+}
+
+// HandleNotifications serves the requests to the WebSocket endpoint of the notifications with the given handler.
+// Like the requests to the api service, they need a session.
+func (s *Server) HandleNotifications(handler http.HandlerFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.notifications = handler
+}
+
+// Requests returns the requests received by the server, in the order they were received
+func (s *Server) Requests() []RecordedRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// RequestCount returns the number of requests received with the given method and path.
+// An empty method matches any method.
+func (s *Server) RequestCount(method, path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for _, request := range s.requests {
+		if (method == "" || request.Method == method) && request.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// ResetRequests clears the recorded requests
+func (s *Server) ResetRequests() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.lock.Lock()
+	s.requests = append(s.requests, RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	fault := s.matchFault(r.Method, r.URL.Path)
+	s.lock.Unlock()
+
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.MalformedJSON {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"malformed": [`)
+			return
+		}
+		if fault.StatusCode != 0 {
+			for key, values := range fault.Header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(fault.StatusCode)
+			fmt.Fprint(w, fault.Body)
+			return
+		}
+	}
+
+	s.lock.Lock()
+	if handler := s.apiHandler(r.URL.Path); handler != nil {
+		authenticated := s.authenticated(r)
+		s.lock.Unlock()
+		if !authenticated {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		// The handler is called without the lock, as it may call the server
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(apiPath, "/"))
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, strings.TrimSuffix(apiPath, "/"))
+		}
+		handler(w, r)
+		return
+	}
+	defer s.lock.Unlock()
+	switch p := r.URL.Path; {
+	case p == apiPath+"login":
+		s.handleLogin(w, r, body)
+	case p == hydraPath+"token":
+		s.handleHydraToken(w, r, body)
+	case p == authPath+"exchange":
+		s.handleAuthExchange(w, r)
+	case strings.HasPrefix(p, apiPath):
+		if !s.authenticated(r) {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		s.handleAPI(w, r, strings.Split(strings.Trim(strings.TrimPrefix(p, apiPath), "/"), "/"), body)
+	default:
+		s.handleTopologyProcessor(w, r, strings.Split(strings.Trim(strings.TrimPrefix(p, tpPath), "/"), "/"), body)
+	}
+}
+
+// apiHandler returns the handler registered for the api service resource of a path, if any
+func (s *Server) apiHandler(path string) http.HandlerFunc {
+	if path == notificationPath {
+		return s.notifications
+	}
+	if !strings.HasPrefix(path, apiPath) {
+		return nil
+	}
+	return s.apiHandlers[strings.SplitN(strings.TrimPrefix(path, apiPath), "/", 2)[0]]
+}
+
+// matchFault returns the fault to apply to a request, if any
+func (s *Server) matchFault(method, path string) *Fault {
+	for _, rule := range s.faults {
+		if (rule.method != "" && rule.method != method) || rule.path != path {
+			continue
+		}
+		if rule.fault.Times > 0 && rule.hits >= rule.fault.Times {
+			continue
+		}
+		rule.hits++
+		return &rule.fault
+	}
+	return nil
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request, body []byte) {
+	form, err := url.ParseQuery(string(body))
+	if r.Method != http.MethodPost || err != nil {
+		writeError(w, http.StatusBadRequest, "invalid login request")
+		return
+	}
+	if form.Get("username") != s.username || form.Get("password") != s.password {
+		writeError(w, http.StatusUnauthorized, "The username or password is invalid")
+		return
+	}
+	session := fmt.Sprintf("session-%d", s.newID())
+	s.sessions[session] = true
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: session})
+	writeJSON(w, map[string]string{"username": s.username})
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	cookie, err := r.Cookie(SessionCookie)
+	return err == nil && s.sessions[cookie.Value]
+}
+
+func (s *Server) handleHydraToken(w http.ResponseWriter, r *http.Request, body []byte) {
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid token request")
+		return
+	}
+	if s.clientID == "" ||
+		r.FormValue("client_id") != s.clientID ||
+		r.FormValue("client_secret") != s.clientSecret ||
+		r.FormValue("grant_type") != "client_credentials" {
+		writeError(w, http.StatusUnauthorized, "invalid client credentials")
+		return
+	}
+	token := fmt.Sprintf("hydra-token-%d", s.newID())
+	s.hydraTokens[token] = true
+	writeJSON(w, map[string]interface{}{
+		"access_token": token,
+		"expires_in":   3600,
+		"scope":        "",
+		"token_type":   "bearer",
+	})
+}
+
+func (s *Server) handleAuthExchange(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-oauth2") != "hydra" || !s.hydraTokens[r.Header.Get("x-auth-token")] {
+		writeError(w, http.StatusUnauthorized, "invalid hydra token")
+		return
+	}
+	fmt.Fprintf(w, "jwt-%d", s.newID())
+}
+
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request, segments []string, body []byte) {
+	switch segments[0] {
+	case string(api.Resource_Type_Targets):
+		s.handleAPITargets(w, r, segments[1:], body)
+	case string(api.Resource_Type_External_Target):
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, s.apiTargets())
+	case string(api.Resource_Type_Licenses):
+		if r.Method != http.MethodGet || len(segments) != 2 || segments[1] != "summary" {
+			writeError(w, http.StatusNotFound, "resource not found")
+			return
+		}
+		if s.licenseSummary == nil {
+			writeError(w, http.StatusNotFound, "no license installed")
+			return
+		}
+		writeJSON(w, s.licenseSummary)
+	case string(api.Resource_Type_Admin):
+		if r.Method != http.MethodGet || len(segments) != 2 || segments[1] != "versions" {
+			writeError(w, http.StatusNotFound, "resource not found")
+			return
+		}
+		writeJSON(w, s.productVersion)
+	default:
+		writeError(w, http.StatusNotFound, "resource not found")
+	}
+}
+
+func (s *Server) handleAPITargets(w http.ResponseWriter, r *http.Request, segments []string, body []byte) {
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeListing(w, r, s.apiTargets())
+		case http.MethodPost:
+			var input api.Target
+			if err := json.Unmarshal(body, &input); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			probe := s.findProbeByType(input.Type, input.Category)
+			if probe == nil {
+				writeError(w, http.StatusBadRequest,
+					fmt.Sprintf("Probe of type %s and category %s is not registered", input.Type, input.Category))
+				return
+			}
+			tgt := s.addTarget(probe, input.DisplayName, input.InputFields, "")
+			writeJSON(w, s.apiTarget(tgt))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+	tgt, err := s.findTargetByUUID(segments[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.apiTarget(tgt))
+	case http.MethodPost:
+		if r.URL.Query().Get("rediscover") == "true" || r.URL.Query().Get("validate") == "true" {
+			s.discover(tgt)
+		}
+		writeJSON(w, s.apiTarget(tgt))
+	case http.MethodPut:
+		var input api.Target
+		if err := json.Unmarshal(body, &input); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		tgt.inputFields = keepSecrets(tgt.inputFields, input.InputFields)
+		s.discover(tgt)
+		writeJSON(w, s.apiTarget(tgt))
+	case http.MethodDelete:
+		delete(s.targets, tgt.id)
+		writeJSON(w, s.apiTarget(tgt))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleTopologyProcessor(w http.ResponseWriter, r *http.Request, segments []string, body []byte) {
+	switch segments[0] {
+	case string(api.Resource_Type_Probe):
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if len(segments) > 1 && segments[1] == "registration" {
+			registrations := s.registrations
+			if registrations == nil {
+				registrations = []api.ProbeRegistration{}
+			}
+			writeJSON(w, registrations)
+			return
+		}
+		probes := []api.ProbeDescription{}
+		for _, probe := range s.probes {
+			probes = append(probes, *probe)
+		}
+		writeListing(w, r, map[string][]api.ProbeDescription{"probes": probes})
+	case string(api.Resource_Type_Target):
+		s.handleTPTargets(w, r, segments[1:], body)
+	default:
+		writeError(w, http.StatusNotFound, "resource not found")
+	}
+}
+
+func (s *Server) handleTPTargets(w http.ResponseWriter, r *http.Request, segments []string, body []byte) {
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			targets := []api.TargetInfo{}
+			for _, tgt := range s.sortedTargets() {
+				targets = append(targets, s.tpTarget(tgt))
+			}
+			writeListing(w, r, map[string][]api.TargetInfo{"targets": targets})
+		case http.MethodPost:
+			var spec api.TargetSpec
+			if err := json.Unmarshal(body, &spec); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			probe := s.findProbeByID(spec.ProbeID)
+			if probe == nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Probe %d is not registered", spec.ProbeID))
+				return
+			}
+			tgt := s.addTarget(probe, "", spec.InputFields, spec.CommunicationBindingChannel)
+			writeJSON(w, s.tpTarget(tgt))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+	tgt, err := s.findTargetByUUID(segments[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if len(segments) > 1 {
+		if segments[1] != "rediscover" || r.Method != http.MethodPost {
+			writeError(w, http.StatusNotFound, "resource not found")
+			return
+		}
+		s.discover(tgt)
+		writeJSON(w, s.tpTarget(tgt))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.tpTarget(tgt))
+	case http.MethodPut:
+		var spec api.TargetSpec
+		if err := json.Unmarshal(body, &spec); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		tgt.inputFields = spec.InputFields
+		tgt.communicationBindingChannel = spec.CommunicationBindingChannel
+		s.discover(tgt)
+		writeJSON(w, s.tpTarget(tgt))
+	case http.MethodDelete:
+		delete(s.targets, tgt.id)
+		writeJSON(w, s.tpTarget(tgt))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) addTarget(probe *api.ProbeDescription, displayName string, inputFields []*api.InputField,
+	communicationBindingChannel string) *target {
+	tgt := &target{
+		id:                          s.newID(),
+		probe:                       probe,
+		displayName:                 displayName,
+		inputFields:                 inputFields,
+		communicationBindingChannel: communicationBindingChannel,
+	}
+	if tgt.displayName == "" {
+		tgt.displayName = identifier(probe, inputFields)
+	}
+	s.targets[tgt.id] = tgt
+	s.discover(tgt)
+	return tgt
+}
+
+func (s *Server) discover(tgt *target) {
+	tgt.status = s.discoveryStatus
+	tgt.lastValidated = time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func (s *Server) apiTargets() []api.Target {
+	targets := []api.Target{}
+	for _, tgt := range s.sortedTargets() {
+		targets = append(targets, s.apiTarget(tgt))
+	}
+	return targets
+}
+
+func (s *Server) apiTarget(tgt *target) api.Target {
+	return api.Target{
+		UUID:              strconv.FormatInt(tgt.id, 10),
+		Category:          tgt.probe.Category,
+		Type:              tgt.probe.Type,
+		DisplayName:       tgt.displayName,
+		IdentifyingFields: tgt.probe.IdentifyingFields,
+		InputFields:       maskSecrets(tgt.inputFields),
+		Status:            tgt.status,
+		LastValidated:     tgt.lastValidated,
+	}
+}
+
+func (s *Server) tpTarget(tgt *target) api.TargetInfo {
+	return api.TargetInfo{
+		TargetID:    tgt.id,
+		DisplayName: tgt.displayName,
+		TargetSpec: &api.TargetSpec{
+			ProbeID:                     tgt.probe.ID,
+			DerivedTargetIDs:            []string{},
+			InputFields:                 tgt.inputFields,
+			CommunicationBindingChannel: tgt.communicationBindingChannel,
+		},
+		Status:             tgt.status,
+		LastValidationTime: tgt.lastValidated,
+	}
+}
+
+func (s *Server) sortedTargets() []*target {
+	var targets []*target
+	for _, tgt := range s.targets {
+		targets = append(targets, tgt)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].id < targets[j].id
+	})
+	return targets
+}
+
+func (s *Server) findTargetByUUID(uuid string) (*target, error) {
+	id, err := strconv.ParseInt(uuid, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Target %s does not exist", uuid)
+	}
+	tgt, found := s.targets[id]
+	if !found {
+		return nil, fmt.Errorf("Target %s does not exist", uuid)
+	}
+	return tgt, nil
+}
+
+func (s *Server) findProbeByType(probeType, probeCategory string) *api.ProbeDescription {
+	for _, probe := range s.probes {
+		if probe.Type == probeType && probe.Category == probeCategory {
+			return probe
+		}
+	}
+	return nil
+}
+
+func (s *Server) findProbeByID(probeID int64) *api.ProbeDescription {
+	for _, probe := range s.probes {
+		if probe.ID == probeID {
+			return probe
+		}
+	}
+	return nil
+}
+
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// identifier returns the value of the first identifying field of a target, which is used as its display name
+func identifier(probe *api.ProbeDescription, inputFields []*api.InputField) string {
+	names := append(append([]string(nil), probe.IdentifyingFields...), "targetIdentifier", "nameOrAddress")
+	for _, name := range names {
+		for _, inputField := range inputFields {
+			if inputField.Name == name {
+				return inputField.Value
+			}
+		}
+	}
+	return ""
+}
+
+// keepSecrets keeps the current values of the secret fields updated with their masked value, like the api service does
+func keepSecrets(current, updated []*api.InputField) []*api.InputField {
+	var inputFields []*api.InputField
+	for _, inputField := range updated {
+		field := *inputField
+		if field.IsSecret && field.Value == "*****" {
+			for _, currentField := range current {
+				if currentField.Name == field.Name {
+					field.Value = currentField.Value
+				}
+			}
+		}
+		inputFields = append(inputFields, &field)
+	}
+	return inputFields
+}
+
+// maskSecrets hides the values of secret fields, like the api service does
+func maskSecrets(inputFields []*api.InputField) []*api.InputField {
+	var masked []*api.InputField
+	for _, inputField := range inputFields {
+		field := *inputField
+		if field.IsSecret {
+			field.Value = "*****"
+		}
+		masked = append(masked, &field)
+	}
+	return masked
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeListing writes a listing with an ETag, or only the status 304 Not Modified if the request
+// has a matching If-None-Match header
+func writeListing(w http.ResponseWriter, r *http.Request, v interface{}) {
+	content, _ := json.Marshal(v)
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(content))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(&api.APIErrorDTO{ResponseType: statusCode, Message: message})
+}
@@ -0,0 +1,290 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func login(t *testing.T, s *Server, username, password string) (*http.Cookie, int) {
+	resp, err := s.Client().Post(s.URL().String()+"/vmturbo/rest/login",
+		"application/x-www-form-urlencoded",
+		strings.NewReader(fmt.Sprintf("username=%s&password=%s", username, password)))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer resp.Body.Close()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == SessionCookie {
+			return cookie, resp.StatusCode
+		}
+	}
+	return nil, resp.StatusCode
+}
+
+func do(t *testing.T, s *Server, method, path string, cookie *http.Cookie, body interface{}) (int, []byte) {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, _ := http.NewRequest(method, s.URL().String()+path, reader)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer resp.Body.Close()
+	content, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, content
+}
+
+func TestServer_Login(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+
+	cookie, statusCode := login(t, s, "foo", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, statusCode)
+	assert.Nil(t, cookie)
+
+	statusCode, _ = do(t, s, "GET", "/vmturbo/rest/targets", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, statusCode)
+
+	cookie, statusCode = login(t, s, "foo", "bar")
+	assert.Equal(t, http.StatusOK, statusCode)
+	statusCode, _ = do(t, s, "GET", "/vmturbo/rest/targets", cookie, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	s.ExpireSessions()
+	statusCode, _ = do(t, s, "GET", "/vmturbo/rest/targets", cookie, nil)
+	assert.Equal(t, http.StatusUnauthorized, statusCode)
+}
+
+func TestServer_HydraTokenExchange(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	s.SetClientCredentials("id", "secret")
+
+	table := []struct {
+		clientSecret       string
+		expectedStatusCode int
+	}{
+		{"wrong", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+	}
+	for _, item := range table {
+		payload := &bytes.Buffer{}
+		writer := multipart.NewWriter(payload)
+		writer.WriteField("client_id", "id")
+		writer.WriteField("client_secret", item.clientSecret)
+		writer.WriteField("grant_type", "client_credentials")
+		writer.Close()
+		resp, err := s.Client().Post(s.URL().String()+"/oauth2/token", writer.FormDataContentType(), payload)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		var token map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&token)
+		resp.Body.Close()
+		assert.Equal(t, item.expectedStatusCode, resp.StatusCode)
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		req, _ := http.NewRequest("POST", s.URL().String()+"/vmturbo/auth/exchange", nil)
+		req.Header.Set("x-oauth2", "hydra")
+		req.Header.Set("x-auth-token", token["access_token"].(string))
+		resp, err = s.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		jwt, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(string(jwt), "jwt-"))
+	}
+}
+
+func TestServer_Targets(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	probeID := s.AddProbe("Kubernetes", "Cloud Native", "targetIdentifier")
+	cookie, _ := login(t, s, "foo", "bar")
+
+	// Unknown probe type
+	statusCode, _ := do(t, s, "POST", "/vmturbo/rest/targets", cookie, &api.Target{Type: "vCenter"})
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	// Add a target via the api service and look it up via the topology processor
+	statusCode, content := do(t, s, "POST", "/vmturbo/rest/targets", cookie, &api.Target{
+		Category: "Cloud Native",
+		Type:     "Kubernetes",
+		InputFields: []*api.InputField{
+			{Name: "targetIdentifier", Value: "cluster"},
+			{Name: "password", Value: "secret", IsSecret: true},
+		},
+	})
+	assert.Equal(t, http.StatusOK, statusCode)
+	var added api.Target
+	assert.NoError(t, json.Unmarshal(content, &added))
+	assert.Equal(t, "cluster", added.DisplayName)
+	assert.Equal(t, ValidatedStatus, added.Status)
+	assert.Equal(t, "*****", added.InputFields[1].Value)
+
+	statusCode, content = do(t, s, "GET", "/target/"+added.UUID, nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	var info api.TargetInfo
+	assert.NoError(t, json.Unmarshal(content, &info))
+	assert.Equal(t, probeID, info.TargetSpec.ProbeID)
+	assert.Equal(t, "secret", info.TargetSpec.InputFields[1].Value)
+
+	// Rediscover the target with a failure
+	s.SetDiscoveryStatus("Validation failed")
+	statusCode, _ = do(t, s, "POST", "/target/"+added.UUID+"/rediscover", nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "Validation failed", s.Targets()[0].Status)
+
+	// Delete the target via the topology processor
+	statusCode, _ = do(t, s, "DELETE", "/target/"+added.UUID, nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Empty(t, s.Targets())
+	statusCode, _ = do(t, s, "GET", "/vmturbo/rest/targets/"+added.UUID, cookie, nil)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
+
+func TestServer_InjectFault(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	s.InjectFault("GET", "/probe", Fault{StatusCode: http.StatusBadGateway, Body: "bad gateway", Times: 1})
+	s.InjectFault("GET", "/probe", Fault{MalformedJSON: true, Times: 1})
+	s.InjectFault("", "/target", Fault{Latency: 50 * time.Millisecond})
+
+	statusCode, content := do(t, s, "GET", "/probe", nil, nil)
+	assert.Equal(t, http.StatusBadGateway, statusCode)
+	assert.Equal(t, "bad gateway", string(content))
+
+	statusCode, content = do(t, s, "GET", "/probe", nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Error(t, json.Unmarshal(content, &map[string]interface{}{}))
+
+	statusCode, content = do(t, s, "GET", "/probe", nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.NoError(t, json.Unmarshal(content, &map[string]interface{}{}))
+
+	start := time.Now()
+	statusCode, _ = do(t, s, "GET", "/target", nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	s.ClearFaults()
+	statusCode, _ = do(t, s, "GET", "/target", nil, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+}
+
+func TestServer_Requests(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+
+	do(t, s, "GET", "/probe", nil, nil)
+	do(t, s, "POST", "/target?foo=bar", nil, &api.TargetSpec{ProbeID: 1})
+	do(t, s, "GET", "/probe", nil, nil)
+
+	requests := s.Requests()
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, "POST", requests[1].Method)
+	assert.Equal(t, "/target", requests[1].Path)
+	assert.Equal(t, "bar", requests[1].Query.Get("foo"))
+	assert.Contains(t, string(requests[1].Body), `"probeId":"1"`)
+	assert.Equal(t, 2, s.RequestCount("GET", "/probe"))
+	assert.Equal(t, 1, s.RequestCount("", "/target"))
+
+	s.ResetRequests()
+	assert.Empty(t, s.Requests())
+}
+
+func TestServer_HandleAPI(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	s.HandleAPI("actions", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	})
+	s.HandleAPI("targets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	s.InjectFault("GET", "/vmturbo/rest/actions/2", Fault{StatusCode: http.StatusBadGateway, Times: 1})
+
+	status, _ := do(t, s, "GET", "/vmturbo/rest/actions/1", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	cookie, _ := login(t, s, "foo", "bar")
+	status, content := do(t, s, "POST", "/vmturbo/rest/actions/1", cookie, "accept")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `POST /actions/1 "accept"`, string(content))
+	status, _ = do(t, s, "GET", "/vmturbo/rest/actions/2", cookie, nil)
+	assert.Equal(t, http.StatusBadGateway, status)
+	// A handler replaces the simulation of a resource
+	status, _ = do(t, s, "GET", "/vmturbo/rest/targets", cookie, nil)
+	assert.Equal(t, http.StatusTeapot, status)
+	status, _ = do(t, s, "GET", "/vmturbo/rest/markets", cookie, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, 3, s.RequestCount("", "/vmturbo/rest/actions/1")+s.RequestCount("", "/vmturbo/rest/actions/2"))
+
+	s.HandleNotifications(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	})
+	status, _ = do(t, s, "GET", "/vmturbo/messages", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	_, content = do(t, s, "GET", "/vmturbo/messages", cookie, nil)
+	assert.Equal(t, "/vmturbo/messages", string(content))
+}
+
+func TestServer_ProductVersion(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	cookie, _ := login(t, s, "foo", "bar")
+	status, content := do(t, s, http.MethodGet, "/vmturbo/rest/admin/versions", cookie, nil)
+	assert.Equal(t, http.StatusOK, status)
+	var version api.ProductVersion
+	assert.NoError(t, json.Unmarshal(content, &version))
+	assert.Equal(t, api.ProductVersion{VersionInfo: DefaultVersionInfo, MarketVersion: api.MarketVersionXL}, version)
+
+	s.SetProductVersion(&api.ProductVersion{Version: "6.4.10", MarketVersion: api.MarketVersionClassic})
+	_, content = do(t, s, http.MethodGet, "/vmturbo/rest/admin/versions", cookie, nil)
+	assert.JSONEq(t, `{"version":"6.4.10","marketVersion":1}`, string(content))
+}
+
+func TestServer_ListingETag(t *testing.T) {
+	s := NewServer("foo", "bar")
+	defer s.Close()
+	s.AddProbe("Kubernetes", "Cloud Native")
+	resp, err := s.Client().Get(s.URL().String() + "/probe")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	request := func() int {
+		req, _ := http.NewRequest(http.MethodGet, s.URL().String()+"/probe", nil)
+		req.Header.Set("If-None-Match", etag)
+		resp, err := s.Client().Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNotModified, request())
+	s.AddProbe("Prometheus", "Guest OS Processes")
+	assert.Equal(t, http.StatusOK, request())
+}
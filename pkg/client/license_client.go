@@ -0,0 +1,171 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// probeCategoryFeatures maps the probe categories to the license features required to add their targets
+var probeCategoryFeatures = map[string][]string{
+	"cloud native":     {"container_control"},
+	"public cloud":     {"public_cloud"},
+	"cloud management": {"cloud_targets"},
+	"storage":          {"storage"},
+	"fabric":           {"fabric"},
+	"network":          {"network_control"},
+	"applications":     {"applications"},
+}
+
+// TargetPreflightCheck checks a target before it is added by TurboClient.AddTarget,
+// so that adding it fails fast with a meaningful error
+type TargetPreflightCheck func(turboClient *TurboClient, target *api.Target) error
+
+// LicenseClient inspects and installs licenses via api service, and checks that targets are licensed.
+type LicenseClient struct {
+	api *APIClient
+}
+
+// NewLicenseClient builds a client managing licenses with the session of the given api service client
+func NewLicenseClient(apiClient *APIClient) *LicenseClient {
+	return &LicenseClient{api: apiClient}
+}
+
+// GetLicenseSummary gets the summary of the licenses installed on the server
+func (c *LicenseClient) GetLicenseSummary() (*api.LicenseSummary, error) {
+	var summary api.LicenseSummary
+	if err := c.api.doJSON("get license summary", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Licenses).SubResource("summary")
+	}, nil, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// ListLicenses lists the licenses installed on the server
+func (c *LicenseClient) ListLicenses() ([]api.License, error) {
+	var licenses []api.License
+	if err := c.api.doJSON("list licenses", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Licenses)
+	}, nil, &licenses); err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
+
+// ValidateLicense validates the given license file without installing it.
+// An invalid license is returned together with the error listing the reasons it is invalid.
+func (c *LicenseClient) ValidateLicense(filename string, content []byte) (*api.License, error) {
+	return c.uploadLicense("validate license", filename, content, true)
+}
+
+// UploadLicense validates and installs the given license file.
+// An invalid license is returned together with the error listing the reasons it is invalid.
+func (c *LicenseClient) UploadLicense(filename string, content []byte) (*api.License, error) {
+	license, err := c.uploadLicense("upload license", filename, content, false)
+	if err != nil {
+		return license, err
+	}
+	glog.V(2).Infof("Successfully uploaded license %v.", filename)
+	return license, nil
+}
+
+// CheckTarget fails if the license is expired, misses the features required by the probe category of the given
+// target, or has no workload capacity left for the target. The workload capacity is only checked for a target which
+// does not exist yet, since adding an existing target again only updates it.
+func (c *LicenseClient) CheckTarget(target *api.Target) error {
+	summary, err := c.GetLicenseSummary()
+	if err != nil {
+		return err
+	}
+	if summary.IsExpired {
+		return fmt.Errorf("license expired on %s", summary.ExpirationDate)
+	}
+	licensed := make(map[string]bool)
+	for _, feature := range summary.Features {
+		licensed[feature] = true
+	}
+	var missing []string
+	for _, feature := range probeCategoryFeatures[strings.ToLower(target.Category)] {
+		if !licensed[feature] {
+			missing = append(missing, feature)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("license misses the features %v required by %s targets", missing, target.Category)
+	}
+	if summary.IsOverLimit ||
+		(summary.NumLicensedEntities > 0 && summary.NumInUseEntities >= summary.NumLicensedEntities) {
+		existingTarget, err := c.api.findTarget(target)
+		if err != nil {
+			return err
+		}
+		if existingTarget == nil {
+			return fmt.Errorf("license workload limit reached: %d of %d %s in use", summary.NumInUseEntities,
+				summary.NumLicensedEntities, summary.CountedEntity)
+		}
+		glog.V(2).Infof("Target %v exists, so the reached workload limit of the license does not prevent adding it.",
+			getTargetId(target))
+	}
+	return nil
+}
+
+// LicensePreflightCheck is a TargetPreflightCheck verifying the license of the server allows to add the target,
+// see LicenseClient.CheckTarget
+func LicensePreflightCheck(turboClient *TurboClient, target *api.Target) error {
+	licenseClient, err := turboClient.LicenseClient()
+	if err != nil {
+		return err
+	}
+	return licenseClient.CheckTarget(target)
+}
+
+// uploadLicense posts the given license file as multipart form data and returns the validated license
+func (c *LicenseClient) uploadLicense(requestDesc, filename string, content []byte, dryRun bool) (*api.License, error) {
+	payload := &bytes.Buffer{}
+	writer := multipart.NewWriter(payload)
+	part, err := writer.CreateFormFile("file", filename)
+	if err == nil {
+		_, err = part.Write(content)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s payload: %v", requestDesc, err)
+	}
+	data := payload.Bytes()
+	response, err := c.api.doWithSession(func() *Request {
+		request := c.api.Post().Resource(api.Resource_Type_Licenses).
+			Header("Content-Type", writer.FormDataContentType()).
+			Header("Accept", "application/json").
+			Data(data)
+		if dryRun {
+			request.Param("dryRun", "true")
+		}
+		return request
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %v", requestDesc, err)
+	}
+	if response.statusCode < 200 || response.statusCode >= 300 {
+		return nil, buildStatusError(requestDesc, response.statusCode, response.status, response.body)
+	}
+	var licenses []api.License
+	if err := json.Unmarshal([]byte(response.body), &licenses); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall %s response: %v", requestDesc, err)
+	}
+	if len(licenses) == 0 {
+		return nil, fmt.Errorf("empty %s response", requestDesc)
+	}
+	license := &licenses[0]
+	if !license.IsValid {
+		return license, fmt.Errorf("license %s is invalid: %s", filename, strings.Join(license.ErrorReasons, ", "))
+	}
+	return license, nil
+}
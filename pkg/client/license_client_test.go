@@ -0,0 +1,148 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+func TestLicenseClient_UploadLicense(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /licenses":
+			fmt.Fprint(w, `[{"uuid":"1","filename":"old.lic","isValid":true}]`)
+		case "POST /licenses":
+			file, header, err := r.FormFile("file")
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, _ := ioutil.ReadAll(file)
+			valid := string(content) == "valid"
+			assert.Equal(t, r.URL.Query().Get("dryRun") == "true", header.Filename == "dry.lic")
+			if !valid {
+				fmt.Fprintf(w, `[{"filename":%q,"isValid":false,"errorReasons":["EXPIRED","INVALID_FEATURE_SET"]}]`, header.Filename)
+				return
+			}
+			fmt.Fprintf(w, `[{"uuid":"2","filename":%q,"isValid":true,"features":["storage"]}]`, header.Filename)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "licenses")
+	defer server.Close()
+	licenseClient := NewLicenseClient(apiClient)
+
+	license, err := licenseClient.ValidateLicense("dry.lic", []byte("valid"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"storage"}, license.Features)
+
+	license, err = licenseClient.UploadLicense("new.lic", []byte("expired"))
+	assert.EqualError(t, err, "license new.lic is invalid: EXPIRED, INVALID_FEATURE_SET")
+	assert.False(t, license.IsValid)
+	license, err = licenseClient.UploadLicense("new.lic", []byte("valid"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", license.UUID)
+
+	licenses, err := licenseClient.ListLicenses()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(licenses))
+}
+
+func TestLicenseClient_CheckTarget(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	turboClient := newTestTurboClient(t, server)
+	licenseClient, err := turboClient.LicenseClient()
+	assert.NoError(t, err)
+
+	table := []struct {
+		summary  *api.LicenseSummary
+		category string
+
+		expectedError string
+	}{
+		{
+			// Valid
+			summary:  &api.LicenseSummary{Features: []string{"container_control"}, NumLicensedEntities: 10, NumInUseEntities: 5},
+			category: "Cloud Native",
+		},
+		{
+			// Category without required features
+			summary:  &api.LicenseSummary{},
+			category: "Hypervisor",
+		},
+		{
+			// Expired
+			summary:  &api.LicenseSummary{IsExpired: true, ExpirationDate: "2020-01-01"},
+			category: "Hypervisor",
+
+			expectedError: "license expired on 2020-01-01",
+		},
+		{
+			// Limit reached
+			summary:  &api.LicenseSummary{NumLicensedEntities: 10, NumInUseEntities: 10, CountedEntity: "VM"},
+			category: "Hypervisor",
+
+			expectedError: "license workload limit reached: 10 of 10 VM in use",
+		},
+		{
+			// Missing features
+			summary:  &api.LicenseSummary{Features: []string{"storage"}},
+			category: "Cloud Native",
+
+			expectedError: "license misses the features [container_control] required by Cloud Native targets",
+		},
+	}
+	for _, item := range table {
+		server.SetLicenseSummary(item.summary)
+		err := licenseClient.CheckTarget(&api.Target{Category: item.category, Type: "foo"})
+		if item.expectedError == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, item.expectedError)
+		}
+	}
+
+	// The workload limit does not prevent adding an existing target again
+	server.AddProbe("Kubernetes", "Cloud Native")
+	_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.NoError(t, err)
+	server.SetLicenseSummary(&api.LicenseSummary{Features: []string{"container_control"}, NumLicensedEntities: 10,
+		NumInUseEntities: 10, CountedEntity: "VM"})
+	assert.NoError(t, licenseClient.CheckTarget(newTestTarget("Kubernetes", "cluster", "p")))
+	assert.EqualError(t, licenseClient.CheckTarget(newTestTarget("Kubernetes", "other", "p")),
+		"license workload limit reached: 10 of 10 VM in use")
+}
+
+func TestTurboClient_AddTargetPreflightCheck(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	config := NewConfigBuilder(server.URL()).
+		BasicAuthentication("foo", "bar").
+		SetTargetPreflightCheck(LicensePreflightCheck).
+		Create()
+	turboClient, err := NewTurboClient(config)
+	assert.NoError(t, err)
+
+	// Without a license
+	_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.Error(t, err)
+
+	server.SetLicenseSummary(&api.LicenseSummary{Features: []string{"public_cloud"}})
+	_, err = turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.EqualError(t, err, "preflight check of Kubernetes target cluster failed: "+
+		"license misses the features [container_control] required by Cloud Native targets")
+	assert.Equal(t, 0, len(server.Targets()))
+
+	server.SetLicenseSummary(&api.LicenseSummary{Features: []string{"container_control"}})
+	outcome, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), TopologyProcessor)
+	assert.NoError(t, err)
+	assert.Equal(t, TargetCreated, outcome)
+	assert.Equal(t, 1, len(server.Targets()))
+}
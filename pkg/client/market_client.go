@@ -0,0 +1,129 @@
+package client
+
+import (
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// notificationCountStat is the name of the statistic counting the notifications of a market
+const notificationCountStat = "numNotifications"
+
+// MarketClient reads the actions, entities, notifications and statistics of the real-time market and of the
+// plan markets via api service.
+type MarketClient struct {
+	api *APIClient
+}
+
+// NotificationCounts counts the notifications of a market, i.e. the risks the actions address, by severity
+type NotificationCounts struct {
+	Total int
+	// Counts by severity, i.e. CRITICAL, MAJOR, MINOR
+	BySeverity map[string]int
+}
+
+// NewMarketClient builds a client reading markets with the session of the given api service client
+func NewMarketClient(apiClient *APIClient) *MarketClient {
+	return &MarketClient{api: apiClient}
+}
+
+// ListMarkets lists the real-time market and the plan markets
+func (c *MarketClient) ListMarkets() ([]api.Market, error) {
+	var markets []api.Market
+	if err := c.api.doJSON("list markets", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Markets)
+	}, nil, &markets); err != nil {
+		return nil, err
+	}
+	return markets, nil
+}
+
+// GetMarket gets the market with the given UUID, including its state
+func (c *MarketClient) GetMarket(marketUUID string) (*api.Market, error) {
+	var market api.Market
+	if err := c.api.doJSON("get market", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Markets).Name(marketUUID)
+	}, nil, &market); err != nil {
+		return nil, err
+	}
+	return &market, nil
+}
+
+// GetMarketNotificationCounts counts the current notifications of the market with the given UUID
+func (c *MarketClient) GetMarketNotificationCounts(marketUUID string) (*NotificationCounts, error) {
+	var snapshots []api.StatSnapshot
+	if err := c.api.doJSON("get market notification counts", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Markets).Name(marketUUID).SubResource("notificationstats")
+	}, nil, &snapshots); err != nil {
+		return nil, err
+	}
+	counts := &NotificationCounts{BySeverity: make(map[string]int)}
+	if len(snapshots) == 0 {
+		return counts, nil
+	}
+	// The last snapshot is the most recent one
+	for _, stat := range snapshots[len(snapshots)-1].Statistics {
+		if stat.Name != notificationCountStat {
+			continue
+		}
+		count := int(stat.Value)
+		counts.Total += count
+		for _, filter := range stat.Filters {
+			if filter.Type == "severity" {
+				counts.BySeverity[filter.Value] += count
+			}
+		}
+	}
+	return counts, nil
+}
+
+// GetMarketActions gets the actions recommended in the market with the given UUID
+func (c *MarketClient) GetMarketActions(marketUUID string) ([]api.Action, error) {
+	var actions []api.Action
+	err := c.api.streamList("get market actions", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Markets).Name(marketUUID).SubResource("actions")
+	}, func(decoder *JSONArrayDecoder) error {
+		var action api.Action
+		if err := decoder.Decode(&action); err != nil {
+			return err
+		}
+		actions = append(actions, action)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// GetMarketEntities gets the entities in the market with the given UUID
+func (c *MarketClient) GetMarketEntities(marketUUID string) ([]api.ServiceEntity, error) {
+	var entities []api.ServiceEntity
+	err := c.api.streamList("get market entities", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Markets).Name(marketUUID).SubResource("entities")
+	}, func(decoder *JSONArrayDecoder) error {
+		var entity api.ServiceEntity
+		if err := decoder.Decode(&entity); err != nil {
+			return err
+		}
+		entities = append(entities, entity)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// GetMarketStats gets the given statistics of the market with the given UUID, i.e. numHosts, numVMs, VCPU
+func (c *MarketClient) GetMarketStats(marketUUID string, statNames ...string) ([]api.StatSnapshot, error) {
+	input := &api.StatPeriodInput{}
+	for _, name := range statNames {
+		input.Statistics = append(input.Statistics, &api.StatInput{Name: name})
+	}
+	var snapshots []api.StatSnapshot
+	if err := c.api.doJSON("get market stats", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Markets).Name(marketUUID).SubResource("stats")
+	}, input, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
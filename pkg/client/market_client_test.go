@@ -0,0 +1,75 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestMarketClient(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /markets":
+			fmt.Fprint(w, `[{"uuid":"Market","displayName":"Market","state":"RUNNING"},{"uuid":"200","state":"SUCCEEDED"}]`)
+		case "GET /markets/Market":
+			fmt.Fprint(w, `{"uuid":"Market","displayName":"Market","state":"RUNNING","environmentType":"HYBRID"}`)
+		case "GET /markets/Market/notificationstats":
+			fmt.Fprint(w, `[{"date":"2020-01-01T00:00:00Z","statistics":[{"name":"numNotifications","value":1}]},`+
+				`{"date":"2020-01-01T01:00:00Z","statistics":[`+
+				`{"name":"numNotifications","value":3,"filters":[{"type":"severity","value":"CRITICAL"}]},`+
+				`{"name":"numNotifications","value":5,"filters":[{"type":"severity","value":"MINOR"}]},`+
+				`{"name":"numActions","value":7}]}]`)
+		case "GET /markets/200/notificationstats":
+			fmt.Fprint(w, `[]`)
+		case "GET /markets/Market/actions":
+			w.Header().Set("X-Next-Cursor", "1")
+			if r.URL.Query().Get("cursor") == "1" {
+				w.Header().Del("X-Next-Cursor")
+				fmt.Fprint(w, `[{"uuid":"2","actionType":"RESIZE"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"uuid":"1","actionType":"MOVE"}]`)
+		case "GET /markets/Market/entities":
+			fmt.Fprint(w, `[{"uuid":"vm-1","className":"VirtualMachine"},{"uuid":"pm-1","className":"PhysicalMachine"}]`)
+		case "POST /markets/Market/stats":
+			fmt.Fprint(w, `[{"date":"2020-01-01T00:00:00Z","statistics":[{"name":"numVMs","value":10}]}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "markets")
+	defer server.Close()
+	marketClient := NewMarketClient(apiClient)
+
+	markets, err := marketClient.ListMarkets()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(markets))
+
+	market, err := marketClient.GetMarket(api.RealtimeMarketUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, &api.Market{UUID: "Market", DisplayName: "Market", State: "RUNNING", EnvironmentType: "HYBRID"}, market)
+
+	counts, err := marketClient.GetMarketNotificationCounts(api.RealtimeMarketUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, &NotificationCounts{Total: 8, BySeverity: map[string]int{"CRITICAL": 3, "MINOR": 5}}, counts)
+	counts, err = marketClient.GetMarketNotificationCounts("200")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, counts.Total)
+
+	actions, err := marketClient.GetMarketActions(api.RealtimeMarketUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Action{{UUID: "1", ActionType: "MOVE"}, {UUID: "2", ActionType: "RESIZE"}}, actions)
+
+	entities, err := marketClient.GetMarketEntities(api.RealtimeMarketUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entities))
+
+	stats, err := marketClient.GetMarketStats(api.RealtimeMarketUUID, "numVMs")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(10), stats[0].Statistics[0].Value)
+
+	_, err = marketClient.GetMarket("300")
+	assert.Error(t, err)
+}
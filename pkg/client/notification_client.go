@@ -0,0 +1,333 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// notificationEndpoint is the WebSocket endpoint pushing the notifications of api service, next to its REST API,
+// i.e. /vmturbo/messages for the REST API path /vmturbo/rest/
+const notificationEndpoint = "messages"
+
+// maxNotificationSize is the maximum size of a notification message
+const maxNotificationSize = 16 * 1024 * 1024
+
+// closeTimeout is the maximum time to wait for sending the closure of a notification connection
+const closeTimeout = 1 * time.Second
+
+var (
+	defaultSubscriptionBufferSize = 100
+	defaultReconnectMinBackoff    = 1 * time.Second
+	defaultReconnectMaxBackoff    = 1 * time.Minute
+)
+
+// Event is a notification received by a subscription: *ActionEvent, *PlanEvent, *TargetEvent or *ReconnectedEvent
+type Event interface {
+	isEvent()
+}
+
+// ActionEvent notifies the progress of the execution of an action
+type ActionEvent struct {
+	api.ActionNotification
+}
+
+// PlanEvent notifies the progress of a plan
+type PlanEvent struct {
+	api.MarketNotification
+}
+
+// TargetEvent notifies the result of the validation or discovery of a target
+type TargetEvent struct {
+	api.TargetNotification
+	State DiscoveryState
+}
+
+// ReconnectedEvent notifies that the subscription reconnected after losing its connection.
+// Notifications sent while disconnected are lost, so the state of interest should be read again.
+type ReconnectedEvent struct {
+	// Number of connection attempts it took to reconnect
+	Attempts int
+}
+
+func (*ActionEvent) isEvent()      {}
+func (*PlanEvent) isEvent()        {}
+func (*TargetEvent) isEvent()      {}
+func (*ReconnectedEvent) isEvent() {}
+
+// SubscriptionOptions configures a notification subscription
+type SubscriptionOptions struct {
+	// JwtToken gets the JWT authenticating the subscription, i.e. TurboClient.JwtTokenSource.
+	// The login session of the client is used if not set or if it returns an empty token.
+	JwtToken func() (string, error)
+	// Number of events buffered until they are received, defaults to 100
+	BufferSize int
+	// Initial and maximum delays between two reconnection attempts, default to 1 second and 1 minute;
+	// the delay doubles after each failed attempt
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Subscription receives the notifications pushed by api service as events,
+// reconnecting when the connection drops until it is closed or its context is done
+type Subscription struct {
+	client *NotificationClient
+	opts   SubscriptionOptions
+	events chan Event
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NotificationClient subscribes to the notifications of api service over WebSocket, authenticated with the login
+// session of the api service client or with a JWT.
+type NotificationClient struct {
+	api *APIClient
+}
+
+// NewNotificationClient builds a client subscribing to notifications with the session of the given api service client
+func NewNotificationClient(apiClient *APIClient) *NotificationClient {
+	return &NotificationClient{api: apiClient}
+}
+
+// Subscribe connects to the notification endpoint and returns a subscription delivering the notifications
+// as events. It fails if the first connection fails; later connection losses are retried with backoff.
+func (c *NotificationClient) Subscribe(ctx context.Context, opts *SubscriptionOptions) (*Subscription, error) {
+	s := &Subscription{
+		client: c,
+		opts: SubscriptionOptions{
+			BufferSize: defaultSubscriptionBufferSize,
+			MinBackoff: defaultReconnectMinBackoff,
+			MaxBackoff: defaultReconnectMaxBackoff,
+		},
+		done: make(chan struct{}),
+	}
+	if opts != nil {
+		s.opts.JwtToken = opts.JwtToken
+		if opts.BufferSize > 0 {
+			s.opts.BufferSize = opts.BufferSize
+		}
+		if opts.MinBackoff > 0 {
+			s.opts.MinBackoff = opts.MinBackoff
+		}
+		if opts.MaxBackoff > 0 {
+			s.opts.MaxBackoff = opts.MaxBackoff
+		}
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	conn, err := c.connect(ctx, &s.opts)
+	if err != nil {
+		s.cancel()
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully subscribed to notifications.")
+	s.events = make(chan Event, s.opts.BufferSize)
+	go s.run(ctx, conn)
+	return s, nil
+}
+
+// Events returns the channel delivering the events, which is closed once the subscription is done
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close closes the connection and waits for the events channel to be closed
+func (s *Subscription) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// run receives the notifications until the subscription is done, reconnecting when the connection drops
+func (s *Subscription) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(s.done)
+	defer close(s.events)
+	for {
+		// Close the connection when the subscription is done, which unblocks the read
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				closeConnection(conn)
+			case <-stop:
+			}
+		}()
+		err := s.receive(ctx, conn)
+		close(stop)
+		closeConnection(conn)
+		if ctx.Err() != nil {
+			glog.V(2).Infof("Notification subscription closed.")
+			return
+		}
+		glog.Warningf("Lost connection to notifications: %v", err)
+		if conn = s.reconnect(ctx); conn == nil {
+			return
+		}
+	}
+}
+
+// receive reads the notifications from the connection and sends them as events until the connection fails
+func (s *Subscription) receive(ctx context.Context, conn *websocket.Conn) error {
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		event, err := decodeNotification(messageType, message)
+		if err != nil {
+			glog.Warningf("Failed to decode notification %q: %v", message, err)
+			continue
+		}
+		if event == nil {
+			glog.V(4).Infof("Ignoring notification %q", message)
+			continue
+		}
+		if !s.send(ctx, event) {
+			return ctx.Err()
+		}
+	}
+}
+
+// reconnect connects again with exponential backoff. It returns nil if the subscription is done first.
+func (s *Subscription) reconnect(ctx context.Context) *websocket.Conn {
+	backoff := s.opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		conn, err := s.client.connect(ctx, &s.opts)
+		if err == nil {
+			glog.V(2).Infof("Reconnected to notifications after %d attempts.", attempt)
+			if !s.send(ctx, &ReconnectedEvent{Attempts: attempt}) {
+				closeConnection(conn)
+				return nil
+			}
+			return conn
+		}
+		if backoff *= 2; backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+		glog.Warningf("Failed to reconnect to notifications (attempt %d), retrying in %v: %v", attempt, backoff, err)
+	}
+}
+
+func (s *Subscription) send(ctx context.Context, event Event) bool {
+	select {
+	case s.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// connect performs the WebSocket handshake with the notification endpoint,
+// authenticated with the JWT if any or with the login session
+func (c *NotificationClient) connect(ctx context.Context, opts *SubscriptionOptions) (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+	if transport, ok := c.api.client.Transport.(*http.Transport); ok {
+		// Reach api service the same way as its REST API
+		dialer.Proxy = transport.Proxy
+		dialer.NetDialContext = transport.DialContext
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	jwt := ""
+	if opts.JwtToken != nil {
+		var err error
+		if jwt, err = opts.JwtToken(); err != nil {
+			return nil, fmt.Errorf("failed to get JWT: %v", err)
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		header := http.Header{}
+		var cookie *http.Cookie
+		if jwt != "" {
+			header.Set("x-auth-token", jwt)
+		} else if !c.api.Unauthenticated {
+			var err error
+			if cookie, err = c.api.login(); err != nil {
+				return nil, fmt.Errorf("failed to login: %w", err)
+			}
+			header.Set("Cookie", fmt.Sprintf("%s=%s", cookie.Name, cookie.Value))
+		}
+		conn, response, err := dialer.DialContext(ctx, c.notificationURL().String(), header)
+		if err == nil {
+			conn.SetReadLimit(maxNotificationSize)
+			return conn, nil
+		}
+		if response == nil {
+			return nil, fmt.Errorf("notification subscription request failed: %w", err)
+		}
+		// The body of a failed handshake response is already read, up to a small size
+		content, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode == http.StatusUnauthorized && cookie != nil && attempt == 1 {
+			// The session expired, login again
+			c.api.invalidateSession(cookie)
+			continue
+		}
+		if response.StatusCode == http.StatusSwitchingProtocols {
+			return nil, fmt.Errorf("invalid notification subscription handshake: %v", err)
+		}
+		return nil, buildStatusError("notification subscription", response.StatusCode, response.Status,
+			string(content))
+	}
+}
+
+// notificationURL returns the URL of the WebSocket endpoint of the notifications,
+// located next to the REST API path of api service
+func (c *NotificationClient) notificationURL() *url.URL {
+	endpoint := *c.api.baseURL
+	endpoint.Path = path.Join(path.Dir(path.Join("/", c.api.apiPath)), notificationEndpoint)
+	if endpoint.Scheme == "https" {
+		endpoint.Scheme = "wss"
+	} else {
+		endpoint.Scheme = "ws"
+	}
+	return &endpoint
+}
+
+// closeConnection sends a normal closure to api service and closes the connection
+func closeConnection(conn *websocket.Conn) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(closeTimeout))
+	conn.Close()
+}
+
+// decodeNotification decodes a notification message into an event, or nil if the notification is not supported.
+// Binary messages hold protobuf encoded notifications, and text messages JSON encoded ones.
+func decodeNotification(messageType int, message []byte) (Event, error) {
+	var notification api.Notification
+	switch messageType {
+	case websocket.BinaryMessage:
+		if err := unmarshalNotification(message, &notification); err != nil {
+			return nil, err
+		}
+	case websocket.TextMessage:
+		if err := json.Unmarshal(message, &notification); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported message type %d", messageType)
+	}
+	switch {
+	case notification.Action != nil:
+		return &ActionEvent{*notification.Action}, nil
+	case notification.Market != nil:
+		return &PlanEvent{*notification.Market}, nil
+	case notification.Target != nil:
+		return &TargetEvent{
+			TargetNotification: *notification.Target,
+			State:              parseDiscoveryState(notification.Target.Status),
+		}, nil
+	}
+	return nil, nil
+}
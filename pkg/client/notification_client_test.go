@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// acceptWebSocket completes the WebSocket handshake of the given request as a server
+func acceptWebSocket(t *testing.T, w http.ResponseWriter, r *http.Request) *websocket.Conn {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestNotificationClient_Subscribe(t *testing.T) {
+	var lock sync.Mutex
+	connections := 0
+	apiClient, server := newTestAPIClient(nil)
+	defer server.Close()
+	server.HandleNotifications(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		connections++
+		connection := connections
+		lock.Unlock()
+		switch connection {
+		case 1:
+			conn := acceptWebSocket(t, w, r)
+			for _, message := range []string{
+				`{"actionNotification":{"actionId":"1","state":"IN_PROGRESS","progressPercentage":50}}`,
+				`{"reportNotification":{"reportId":"1"}}`,
+				`{"marketNotification":`,
+				`{"marketNotification":{"marketId":"200","state":"RUNNING","progressPercentage":30}}`,
+				`{"targetNotification":{"targetId":"300","type":"VALIDATION","status":"Validated"}}`,
+			} {
+				conn.WriteMessage(websocket.TextMessage, []byte(message))
+			}
+			// Protobuf encoded action notification {actionId: "1", state: "SUCCEEDED", progressPercentage: 100}
+			conn.WriteMessage(websocket.BinaryMessage, []byte{
+				0x12, 0x10, 0x0a, 0x01, '1', 0x12, 0x09, 'S', 'U', 'C', 'C', 'E', 'E', 'D', 'E', 'D', 0x18, 0x64})
+			// Drop the connection, the session expiring meanwhile
+			server.ExpireSessions()
+			conn.Close()
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			conn := acceptWebSocket(t, w, r)
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"targetNotification":{"targetId":"300","type":"DISCOVERY","status":"Connection refused"}}`))
+			// Wait for the closure of the subscription
+			conn.ReadMessage()
+			conn.Close()
+		}
+	})
+	notificationClient := NewNotificationClient(apiClient)
+
+	subscription, err := notificationClient.Subscribe(context.Background(),
+		&SubscriptionOptions{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	assert.NoError(t, err)
+
+	var events []Event
+	for event := range subscription.Events() {
+		events = append(events, event)
+		if len(events) == 6 {
+			break
+		}
+	}
+	assert.Equal(t, []Event{
+		&ActionEvent{api.ActionNotification{ActionID: "1", State: "IN_PROGRESS", ProgressPercentage: 50}},
+		&PlanEvent{api.MarketNotification{MarketID: "200", State: "RUNNING", ProgressPercentage: 30}},
+		&TargetEvent{api.TargetNotification{TargetID: "300", Type: "VALIDATION", Status: "Validated"}, DiscoverySucceeded},
+		&ActionEvent{api.ActionNotification{ActionID: "1", State: "SUCCEEDED", ProgressPercentage: 100}},
+		&ReconnectedEvent{Attempts: 2},
+		&TargetEvent{api.TargetNotification{TargetID: "300", Type: "DISCOVERY", Status: "Connection refused"}, DiscoveryFailed},
+	}, events)
+
+	subscription.Close()
+	_, open := <-subscription.Events()
+	assert.False(t, open)
+}
+
+func TestNotificationClient_SubscribeWithJwt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/turbo/vmturbo/messages" || r.Header.Get("x-auth-token") != "jwt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err := r.Cookie(SessionCookie)
+		assert.Error(t, err)
+		conn := acceptWebSocket(t, w, r)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"actionNotification":{"actionId":"1","state":"SUCCEEDED"}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	// The notification endpoint is located next to the REST API path of api service
+	notificationClient := NewNotificationClient(&APIClient{
+		RESTClient: NewRESTClient(server.Client(), serverURL, "/turbo/vmturbo/rest/"),
+	})
+
+	_, err := notificationClient.Subscribe(context.Background(), &SubscriptionOptions{
+		JwtToken: func() (string, error) { return "expired", nil },
+	})
+	assert.EqualError(t, err, "unsuccessful notification subscription response: 401 Unauthorized.")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subscription, err := notificationClient.Subscribe(ctx, &SubscriptionOptions{
+		JwtToken: func() (string, error) { return "jwt", nil },
+	})
+	assert.NoError(t, err)
+	event := <-subscription.Events()
+	assert.Equal(t, &ActionEvent{api.ActionNotification{ActionID: "1", State: "SUCCEEDED"}}, event)
+
+	// The events channel is closed once the context is done
+	cancel()
+	for range subscription.Events() {
+	}
+}
+
+func TestDecodeNotification(t *testing.T) {
+	table := []struct {
+		messageType   int
+		message       []byte
+		expectedEvent Event
+		expectsError  bool
+	}{
+		// Protobuf encoded target notification {targetId: "300", type: "VALIDATION", status: "Validated"}
+		{
+			messageType: websocket.BinaryMessage,
+			message: []byte{0x22, 0x1c, 0x0a, 0x03, '3', '0', '0', 0x12, 0x0a, 'V', 'A', 'L', 'I', 'D', 'A', 'T', 'I', 'O', 'N',
+				0x1a, 0x09, 'V', 'a', 'l', 'i', 'd', 'a', 't', 'e', 'd'},
+			expectedEvent: &TargetEvent{api.TargetNotification{TargetID: "300", Type: "VALIDATION", Status: "Validated"},
+				DiscoverySucceeded},
+		},
+		// Protobuf encoded market notification {marketId: "200", progressPercentage: 30} with a broadcast ID
+		// and unknown varint, fixed32 and fixed64 fields
+		{
+			messageType: websocket.BinaryMessage,
+			message: []byte{0x0a, 0x01, 'b', 0x1a, 0x17, 0x0a, 0x03, '2', '0', '0', 0x18, 0x1e, 0x28, 0x01,
+				0x35, 0, 0, 0, 0, 0x39, 0, 0, 0, 0, 0, 0, 0, 0},
+			expectedEvent: &PlanEvent{api.MarketNotification{MarketID: "200", ProgressPercentage: 30}},
+		},
+		// Protobuf encoded notification of an unsupported type
+		{
+			messageType: websocket.BinaryMessage,
+			message:     []byte{0x2a, 0x02, 0x0a, 0x00},
+		},
+		// Truncated protobuf message
+		{
+			messageType:  websocket.BinaryMessage,
+			message:      []byte{0x12, 0x05, 0x0a},
+			expectsError: true,
+		},
+		// Protobuf group, which is not supported
+		{
+			messageType:  websocket.BinaryMessage,
+			message:      []byte{0x13, 0x14},
+			expectsError: true,
+		},
+		// JSON encoded notification
+		{
+			messageType:   websocket.TextMessage,
+			message:       []byte(`{"actionNotification":{"actionId":"1","state":"FAILED","description":"timeout"}}`),
+			expectedEvent: &ActionEvent{api.ActionNotification{ActionID: "1", State: "FAILED", Description: "timeout"}},
+		},
+		// Control message
+		{
+			messageType:  websocket.PingMessage,
+			expectsError: true,
+		},
+	}
+	for _, item := range table {
+		event, err := decodeNotification(item.messageType, item.message)
+		if item.expectsError {
+			assert.Error(t, err, "%v", item.message)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, item.expectedEvent, event)
+		}
+	}
+}
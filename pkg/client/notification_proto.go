@@ -0,0 +1,141 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// Wire types of the protobuf encoding
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// Field numbers of the protobuf encoded notifications, which hold the fields of their JSON encoding in order
+const (
+	notificationBroadcastID = 1
+	notificationAction      = 2
+	notificationMarket      = 3
+	notificationTarget      = 4
+
+	actionNotificationID          = 1
+	actionNotificationState       = 2
+	actionNotificationProgress    = 3
+	actionNotificationDescription = 4
+
+	marketNotificationID       = 1
+	marketNotificationState    = 2
+	marketNotificationProgress = 3
+
+	targetNotificationID     = 1
+	targetNotificationType   = 2
+	targetNotificationStatus = 3
+)
+
+var errInvalidProto = errors.New("invalid protobuf message")
+
+// unmarshalNotification decodes a protobuf encoded notification. Unknown fields are ignored.
+func unmarshalNotification(message []byte, notification *api.Notification) error {
+	return readProtoFields(message, func(number int, value uint64, data []byte) error {
+		switch number {
+		case notificationBroadcastID:
+			notification.BroadcastID = string(data)
+		case notificationAction:
+			action := &api.ActionNotification{}
+			notification.Action = action
+			return readProtoFields(data, func(number int, value uint64, data []byte) error {
+				switch number {
+				case actionNotificationID:
+					action.ActionID = string(data)
+				case actionNotificationState:
+					action.State = string(data)
+				case actionNotificationProgress:
+					action.ProgressPercentage = int(value)
+				case actionNotificationDescription:
+					action.Description = string(data)
+				}
+				return nil
+			})
+		case notificationMarket:
+			market := &api.MarketNotification{}
+			notification.Market = market
+			return readProtoFields(data, func(number int, value uint64, data []byte) error {
+				switch number {
+				case marketNotificationID:
+					market.MarketID = string(data)
+				case marketNotificationState:
+					market.State = string(data)
+				case marketNotificationProgress:
+					market.ProgressPercentage = int(value)
+				}
+				return nil
+			})
+		case notificationTarget:
+			target := &api.TargetNotification{}
+			notification.Target = target
+			return readProtoFields(data, func(number int, value uint64, data []byte) error {
+				switch number {
+				case targetNotificationID:
+					target.TargetID = string(data)
+				case targetNotificationType:
+					target.Type = string(data)
+				case targetNotificationStatus:
+					target.Status = string(data)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+// readProtoFields calls field with the number and the value of each field of a protobuf message, in order.
+// The value of a varint field is passed as an integer, and the value of a length-delimited field as bytes.
+// Fixed size fields are skipped.
+func readProtoFields(message []byte, field func(number int, value uint64, data []byte) error) error {
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return errInvalidProto
+		}
+		message = message[n:]
+		number, wireType := int(key>>3), key&0x7
+		var value uint64
+		var data []byte
+		switch wireType {
+		case protoVarint:
+			if value, n = binary.Uvarint(message); n <= 0 {
+				return errInvalidProto
+			}
+			message = message[n:]
+		case protoBytes:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || length > uint64(len(message)-n) {
+				return errInvalidProto
+			}
+			data = message[n : n+int(length)]
+			message = message[n+int(length):]
+		case protoFixed64, protoFixed32:
+			size := 8
+			if wireType == protoFixed32 {
+				size = 4
+			}
+			if len(message) < size {
+				return errInvalidProto
+			}
+			message = message[size:]
+			continue
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+		if err := field(number, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
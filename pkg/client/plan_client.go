@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+var (
+	defaultPlanPollInterval = 10 * time.Second
+	defaultPlanTimeout      = 1 * time.Hour
+)
+
+// PlanClient runs what-if plans via api service, from their scenario to their actions and statistics.
+type PlanClient struct {
+	api *APIClient
+}
+
+// PlanRunOptions configures how a plan is run
+type PlanRunOptions struct {
+	// Name of the plan market, defaults to the name of the scenario
+	PlanMarketName string
+	// Whether the plan ignores the placement constraints of the entities
+	IgnoreConstraints bool
+	// Interval between two polls of the plan progress, defaults to 10 seconds
+	PollInterval time.Duration
+	// Maximum time to wait for the plan to complete, defaults to 1 hour
+	Timeout time.Duration
+}
+
+// PlanResult is the result of a completed plan
+type PlanResult struct {
+	Scenario *api.Scenario
+	Market   *api.Market
+	// Actions recommended by the plan
+	Actions []api.Action
+}
+
+// NewPlanClient builds a client running plans with the session of the given api service client
+func NewPlanClient(apiClient *APIClient) *PlanClient {
+	return &PlanClient{api: apiClient}
+}
+
+// CreateScenario creates a plan scenario and returns it with its UUID
+func (c *PlanClient) CreateScenario(scenario *api.Scenario) (*api.Scenario, error) {
+	var created api.Scenario
+	if err := c.api.doJSON("create scenario", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Scenarios)
+	}, scenario, &created); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully created scenario %v with UUID %v.", created.DisplayName, created.UUID)
+	return &created, nil
+}
+
+// DeleteScenario deletes a plan scenario
+func (c *PlanClient) DeleteScenario(scenarioUUID string) error {
+	return c.api.doJSON("delete scenario", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Scenarios).Name(scenarioUUID)
+	}, nil, nil)
+}
+
+// StartPlan starts a plan running the given scenario against the real-time market
+// and returns the plan market, which the progress and results of the plan are read from
+func (c *PlanClient) StartPlan(scenarioUUID string, opts *PlanRunOptions) (*api.Market, error) {
+	if opts == nil {
+		opts = &PlanRunOptions{}
+	}
+	var market api.Market
+	if err := c.api.doJSON("start plan", func() *Request {
+		request := c.api.Post().Resource(api.Resource_Type_Markets).Name(api.RealtimeMarketUUID).
+			SubResource(string(api.Resource_Type_Scenarios), scenarioUUID).
+			Param("ignore_constraints", strconv.FormatBool(opts.IgnoreConstraints))
+		if opts.PlanMarketName != "" {
+			request.Param("plan_market_name", opts.PlanMarketName)
+		}
+		return request
+	}, nil, &market); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully started plan market %v for scenario %v.", market.UUID, scenarioUUID)
+	return &market, nil
+}
+
+// GetPlanMarket gets the plan market with the given UUID, including the state and progress of the plan
+func (c *PlanClient) GetPlanMarket(marketUUID string) (*api.Market, error) {
+	return NewMarketClient(c.api).GetMarket(marketUUID)
+}
+
+// WaitForPlan polls the progress of a plan until it completes, or the context is done or the timeout
+// in opts expires. A plan which failed or was stopped is reported as an error.
+func (c *PlanClient) WaitForPlan(ctx context.Context, marketUUID string, opts *PlanRunOptions) (*api.Market, error) {
+	pollInterval, timeout := defaultPlanPollInterval, defaultPlanTimeout
+	if opts != nil {
+		if opts.PollInterval > 0 {
+			pollInterval = opts.PollInterval
+		}
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		market, err := c.GetPlanMarket(marketUUID)
+		if err != nil {
+			glog.Warningf("Failed to get progress of plan %s: %v", marketUUID, err)
+		} else {
+			glog.V(4).Infof("Plan %s is %s (%d%%)", marketUUID, market.State, market.StateProgress)
+			switch market.State {
+			case api.PlanStateSucceeded:
+				return market, nil
+			case api.PlanStateFailed, api.PlanStateStopped:
+				return market, fmt.Errorf("plan %s did not complete: %s", marketUUID, market.State)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return market, fmt.Errorf("timed out waiting for plan %s: %v", marketUUID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetPlanActions gets the actions recommended by a plan
+func (c *PlanClient) GetPlanActions(marketUUID string) ([]api.Action, error) {
+	return NewMarketClient(c.api).GetMarketActions(marketUUID)
+}
+
+// GetPlanStats gets the given statistics of a plan, i.e. numHosts, numVMs, VCPU and so on.
+func (c *PlanClient) GetPlanStats(marketUUID string, statNames ...string) ([]api.StatSnapshot, error) {
+	return NewMarketClient(c.api).GetMarketStats(marketUUID, statNames...)
+}
+
+// DeletePlan deletes a plan market and its results
+func (c *PlanClient) DeletePlan(marketUUID string) error {
+	if marketUUID == api.RealtimeMarketUUID {
+		return fmt.Errorf("the real-time market cannot be deleted")
+	}
+	if err := c.api.doJSON("delete plan", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Markets).Name(marketUUID)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully deleted plan market %v.", marketUUID)
+	return nil
+}
+
+// RunPlan creates the scenario, runs a plan with it and blocks until the plan completes,
+// then returns the plan market together with the recommended actions.
+// The plan market is kept so that further results can be fetched; delete it with DeletePlan when done.
+func (c *PlanClient) RunPlan(ctx context.Context, scenario *api.Scenario) (*PlanResult, error) {
+	created, err := c.CreateScenario(scenario)
+	if err != nil {
+		return nil, err
+	}
+	market, err := c.StartPlan(created.UUID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if market, err = c.WaitForPlan(ctx, market.UUID, nil); err != nil {
+		return &PlanResult{Scenario: created, Market: market}, err
+	}
+	actions, err := c.GetPlanActions(market.UUID)
+	if err != nil {
+		return &PlanResult{Scenario: created, Market: market}, err
+	}
+	return &PlanResult{Scenario: created, Market: market, Actions: actions}, nil
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// newTestPlanServer simulates a plan progressing to the given final state
+func newTestPlanServer(t *testing.T, finalState string) (*PlanClient, func(), *[]string) {
+	var lock sync.Mutex
+	var requests []string
+	polls := 0
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/scenarios":
+			var scenario api.Scenario
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&scenario))
+			scenario.UUID = "100"
+			json.NewEncoder(w).Encode(&scenario)
+		case r.Method == "POST" && r.URL.Path == "/markets/Market/scenarios/100":
+			assert.Equal(t, "false", r.URL.Query().Get("ignore_constraints"))
+			fmt.Fprint(w, `{"uuid":"200","state":"RUNNING","stateProgress":0}`)
+		case r.Method == "GET" && r.URL.Path == "/markets/200":
+			polls++
+			if polls < 3 {
+				fmt.Fprintf(w, `{"uuid":"200","state":"RUNNING","stateProgress":%d}`, polls*30)
+				return
+			}
+			fmt.Fprintf(w, `{"uuid":"200","state":%q,"stateProgress":100}`, finalState)
+		case r.Method == "GET" && r.URL.Path == "/markets/200/actions":
+			fmt.Fprint(w, `[{"uuid":"1","actionType":"SUSPEND","target":{"uuid":"pm-1","className":"PhysicalMachine"}}]`)
+		case r.Method == "POST" && r.URL.Path == "/markets/200/stats":
+			var input api.StatPeriodInput
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+			assert.Equal(t, "numHosts", input.Statistics[0].Name)
+			fmt.Fprint(w, `[{"date":"2020-01-01T00:00:00Z","epoch":"PLAN_SOURCE","statistics":[{"name":"numHosts","value":10}]},`+
+				`{"date":"2020-01-01T00:00:00Z","epoch":"PLAN_PROJECTED","statistics":[{"name":"numHosts","value":8}]}]`)
+		case r.Method == "DELETE" && r.URL.Path == "/markets/200":
+			fmt.Fprint(w, `true`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "scenarios", "markets")
+	planClient := NewPlanClient(apiClient)
+	defaultPlanPollInterval = time.Millisecond
+	return planClient, func() {
+		server.Close()
+		defaultPlanPollInterval = 10 * time.Second
+	}, &requests
+}
+
+func TestPlanClient_RunPlan(t *testing.T) {
+	planClient, closeServer, requests := newTestPlanServer(t, api.PlanStateSucceeded)
+	defer closeServer()
+
+	scenario := &api.Scenario{
+		DisplayName: "Decommission hosts",
+		Type:        api.ScenarioTypeDecommissionHost,
+		Scope:       []*api.BaseDTO{{UUID: "cluster-1"}},
+		TopologyChanges: &api.TopologyChanges{
+			RemoveList: []*api.RemoveObject{{Target: &api.BaseDTO{UUID: "pm-1"}}},
+		},
+	}
+	result, err := planClient.RunPlan(context.Background(), scenario)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", result.Scenario.UUID)
+	assert.Equal(t, api.PlanStateSucceeded, result.Market.State)
+	assert.Equal(t, 1, len(result.Actions))
+	assert.Equal(t, "pm-1", result.Actions[0].Target.UUID)
+
+	stats, err := planClient.GetPlanStats(result.Market.UUID, "numHosts")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, float64(8), stats[1].Statistics[0].Value)
+
+	assert.NoError(t, planClient.DeletePlan(result.Market.UUID))
+	assert.Error(t, planClient.DeletePlan(api.RealtimeMarketUUID))
+	assert.Equal(t, []string{
+		"POST /scenarios",
+		"POST /markets/Market/scenarios/100",
+		"GET /markets/200",
+		"GET /markets/200",
+		"GET /markets/200",
+		"GET /markets/200/actions",
+		"POST /markets/200/stats",
+		"DELETE /markets/200",
+	}, *requests)
+}
+
+func TestPlanClient_RunPlanFailure(t *testing.T) {
+	planClient, closeServer, _ := newTestPlanServer(t, api.PlanStateFailed)
+	defer closeServer()
+
+	result, err := planClient.RunPlan(context.Background(), &api.Scenario{DisplayName: "foo"})
+	assert.EqualError(t, err, "plan 200 did not complete: FAILED")
+	assert.Equal(t, api.PlanStateFailed, result.Market.State)
+	assert.Nil(t, result.Actions)
+}
+
+func TestPlanClient_WaitForPlanTimeout(t *testing.T) {
+	planClient, closeServer, _ := newTestPlanServer(t, "RUNNING")
+	defer closeServer()
+
+	market, err := planClient.WaitForPlan(context.Background(), "200",
+		&PlanRunOptions{PollInterval: time.Millisecond, Timeout: 50 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Equal(t, "RUNNING", market.State)
+}
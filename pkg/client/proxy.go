@@ -0,0 +1,169 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// supportedProxySchemes are the proxy schemes supported by http.Transport
+var supportedProxySchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"socks5": true,
+}
+
+// proxyFromEnvironment returns the proxy given by the environment variables, replaced in tests
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// newProxyFunc returns the proxy function of the transport to Turbonomic services.
+// Without a configured proxy, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+// The proxy, either configured or given by the environment, is bypassed for the hosts in noProxy, which defaults
+// to the NO_PROXY environment variable.
+func newProxyFunc(proxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		if noProxy == "" {
+			return proxyFromEnvironment, nil
+		}
+		bypass := parseNoProxy(noProxy)
+		glog.V(2).Infof("Using the proxy of the environment, bypassed for %v", bypass)
+		return func(req *http.Request) (*url.URL, error) {
+			if bypass.matches(req.URL) {
+				return nil, nil
+			}
+			return proxyFromEnvironment(req)
+		}, nil
+	}
+	proxyURL, err := parseProxy(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if noProxy == "" {
+		noProxy = getEnvAny("NO_PROXY", "no_proxy")
+	}
+	bypass := parseNoProxy(noProxy)
+	glog.V(2).Infof("Using proxy %s, bypassed for %v", proxyURL.Redacted(), bypass)
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass.matches(req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// parseProxy parses a proxy address of the form [scheme://][username:password@]host:port.
+// The scheme defaults to http. The credentials may be percent-encoded, and are otherwise taken as is,
+// so that they can contain reserved characters such as @, : and /.
+func parseProxy(proxy string) (*url.URL, error) {
+	scheme, address := "http", proxy
+	if i := strings.Index(proxy, "://"); i >= 0 {
+		scheme, address = strings.ToLower(proxy[:i]), proxy[i+3:]
+	}
+	if !supportedProxySchemes[scheme] {
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", scheme)
+	}
+	var user *url.Userinfo
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		userinfo := address[:i]
+		address = address[i+1:]
+		username, password, hasPassword := strings.Cut(userinfo, ":")
+		username, password = unescapeUserinfo(username), unescapeUserinfo(password)
+		if hasPassword {
+			user = url.UserPassword(username, password)
+		} else {
+			user = url.User(username)
+		}
+	}
+	proxyURL, err := url.Parse(scheme + "://" + address)
+	if err != nil {
+		// The error does not hold the credentials, which were removed from the address
+		return nil, fmt.Errorf("invalid proxy address: %v", err)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address: missing host")
+	}
+	proxyURL.User = user
+	return proxyURL, nil
+}
+
+// unescapeUserinfo decodes percent-encoded credentials, keeping credentials which are not valid encodings as is
+func unescapeUserinfo(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// noProxyList is a list of hosts bypassing the proxy, in the format of the NO_PROXY environment variable
+type noProxyList []string
+
+// parseNoProxy parses a comma-separated list of hosts, domains, IP addresses and CIDR blocks, optionally with ports
+func parseNoProxy(noProxy string) noProxyList {
+	var list noProxyList
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// matches returns true if the given URL bypasses the proxy.
+// An entry matches its host and, for domains, its subdomains; a leading dot or *. matches the subdomains only.
+// An entry with a port only matches that port, and * matches all the hosts.
+func (l noProxyList) matches(u *url.URL) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range l {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(strings.Trim(entryHost, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		if strings.HasPrefix(entryHost, ".") {
+			if strings.HasSuffix(host, entryHost) {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by the requests failing fast when the rate limit of their service is exceeded
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// maxRetryAfter is the longest time the requests to a service are held after a response with Retry-After
+var maxRetryAfter = 10 * time.Minute
+
+// RateLimit limits the rate of the requests to a service
+type RateLimit struct {
+	// Number of requests per second, no limit if 0 but the Retry-After headers are still honoured
+	Rate float64
+	// Maximum number of requests sent at once after being idle, defaults to 1
+	Burst int
+	// Whether the requests exceeding the limit fail with ErrRateLimited instead of waiting
+	FailFast bool
+}
+
+// RateLimiter is a token bucket limiting the rate of the requests to a service. It also holds the requests
+// for the time given by the Retry-After header of the 429 Too Many Requests and 503 Service Unavailable responses.
+// It is safe for concurrent use by multiple goroutines.
+type RateLimiter struct {
+	limit RateLimit
+
+	lock sync.Mutex
+	// Number of tokens in the bucket at the time of the last update, negative if tokens are reserved
+	tokens float64
+	last   time.Time
+	// The time until which the requests are held, after a response with Retry-After
+	retryAfter time.Time
+	now        func() time.Time
+}
+
+// NewRateLimiter builds a rate limiter with a full bucket
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &RateLimiter{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		now:    time.Now,
+	}
+}
+
+// Wait waits until a request can be sent, or until the context is done. It fails fast with ErrRateLimited instead
+// of waiting if configured to.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay, err := l.reserve()
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket and returns the time to wait before sending the request
+func (l *RateLimiter) reserve() (time.Duration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.refill(now)
+	var delay time.Duration
+	if l.retryAfter.After(now) {
+		delay = l.retryAfter.Sub(now)
+	}
+	if l.limit.Rate > 0 && l.tokens < 1 {
+		delay = time.Duration(math.Max(float64(delay), (1-l.tokens)/l.limit.Rate*float64(time.Second)))
+	}
+	if delay > 0 && l.limit.FailFast {
+		return 0, fmt.Errorf("%w, retry in %v", ErrRateLimited, delay.Round(time.Millisecond))
+	}
+	if l.limit.Rate > 0 {
+		l.tokens--
+	}
+	return delay, nil
+}
+
+// release puts back the token of a request which was not sent
+func (l *RateLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.limit.Rate > 0 {
+		l.refill(l.now())
+		l.tokens = math.Min(l.tokens+1, float64(l.limit.Burst))
+	}
+}
+
+// refill adds the tokens accumulated since the last update to the bucket
+func (l *RateLimiter) refill(now time.Time) {
+	if !l.last.IsZero() && l.limit.Rate > 0 {
+		l.tokens = math.Min(l.tokens+now.Sub(l.last).Seconds()*l.limit.Rate, float64(l.limit.Burst))
+	}
+	l.last = now
+}
+
+// observe holds the requests for the time given by the Retry-After header of a 429 or 503 response
+func (l *RateLimiter) observe(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	if retryAfter := now.Add(delay); retryAfter.After(l.retryAfter) {
+		l.retryAfter = retryAfter
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return date.Sub(now), true
+}
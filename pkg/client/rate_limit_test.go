@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+func newTestRateLimiter(limit RateLimit) (*RateLimiter, *time.Time) {
+	limiter := NewRateLimiter(limit)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	limiter, now := newTestRateLimiter(RateLimit{Rate: 10, Burst: 2})
+	for _, expected := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		delay, err := limiter.reserve()
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(expected), float64(delay), float64(time.Millisecond))
+	}
+	// The bucket is refilled over time, up to the burst
+	*now = now.Add(time.Minute)
+	for _, expected := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		delay, err := limiter.reserve()
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(expected), float64(delay), float64(time.Millisecond))
+	}
+	// A request which is not sent gives its token back
+	limiter.release()
+	delay, _ := limiter.reserve()
+	assert.InDelta(t, float64(100*time.Millisecond), float64(delay), float64(time.Millisecond))
+
+	limiter, now = newTestRateLimiter(RateLimit{Rate: 10, Burst: 2, FailFast: true})
+	for i := 0; i < 2; i++ {
+		delay, err := limiter.reserve()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), delay)
+	}
+	_, err := limiter.reserve()
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.EqualError(t, err, "rate limit exceeded, retry in 100ms")
+	*now = now.Add(100 * time.Millisecond)
+	delay, err = limiter.reserve()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), delay)
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1})
+	assert.NoError(t, limiter.Wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestRateLimiter_RetryAfter(t *testing.T) {
+	defer func(max time.Duration) { maxRetryAfter = max }(maxRetryAfter)
+	maxRetryAfter = time.Hour
+	limiter, now := newTestRateLimiter(RateLimit{})
+	table := []struct {
+		statusCode    int
+		retryAfter    string
+		expectedDelay time.Duration
+	}{
+		{http.StatusOK, "", 0},
+		{http.StatusInternalServerError, "10", 0},
+		{http.StatusTooManyRequests, "", 0},
+		{http.StatusTooManyRequests, "invalid", 0},
+		{http.StatusTooManyRequests, "10", 10 * time.Second},
+		{http.StatusServiceUnavailable, now.Add(time.Minute).UTC().Format(http.TimeFormat), time.Minute},
+		// A shorter Retry-After does not shorten the wait
+		{http.StatusServiceUnavailable, "1", time.Minute},
+		{http.StatusTooManyRequests, "7200", time.Hour},
+	}
+	for _, item := range table {
+		limiter.observe(&http.Response{StatusCode: item.statusCode, Header: http.Header{"Retry-After": {item.retryAfter}}})
+		delay, err := limiter.reserve()
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(item.expectedDelay), float64(delay), float64(time.Second), item.retryAfter)
+	}
+	*now = now.Add(2 * time.Hour)
+	delay, _ := limiter.reserve()
+	assert.True(t, delay <= 0)
+}
+
+func TestTurboClient_RateLimit(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	server.AddProbe("Kubernetes", "Cloud Native")
+	turboClient, _ := NewTurboClient(NewConfigBuilder(server.URL()).BasicAuthentication("foo", "bar").
+		SetRateLimit(TopologyProcessor, RateLimit{Rate: 20}).
+		SetRateLimit(API, RateLimit{Rate: 1, Burst: 2, FailFast: true}).
+		Create())
+
+	// The requests to topology processor wait
+	tpClient, _ := turboClient.TopologyProcessorClient()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := tpClient.GetProbes()
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+
+	// The requests to api service fail fast, the login and the listing of the targets taking the burst
+	_, err := turboClient.AddTarget(newTestTarget("Kubernetes", "cluster", "p"), API)
+	assert.True(t, errors.Is(err, ErrRateLimited), "%v", err)
+	assert.Equal(t, 1, server.RequestCount("GET", "/vmturbo/rest/targets"))
+	assert.Equal(t, 0, server.RequestCount("POST", "/vmturbo/rest/targets"))
+
+	// The requests are held after a response with Retry-After
+	server.InjectFault("GET", "/probe", fake.Fault{StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": {"1"}}, Times: 1})
+	_, err = tpClient.GetProbes()
+	assert.EqualError(t, err, "unsuccessful get probe response: 429 Too Many Requests.")
+	start = time.Now()
+	_, err = tpClient.GetProbes()
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 900*time.Millisecond)
+}
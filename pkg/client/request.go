@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -31,21 +32,46 @@ type Request struct {
 
 	resource     api.ResourceType
 	resourceName string
+	subpath      string
+	// The escaped path of the sub-resource
+	rawSubpath string
 
 	data    io.Reader
 	headers map[string]string
 
+	ctx context.Context
+
+	// The maximum number of bytes read from a buffered response body, 0 means no limit.
+	maxResponseSize int64
+
+	// The cache of the listings, if any
+	cache   *ResponseCache
+	noCache bool
+
+	// The limiter of the rate of the requests to the service, if any
+	limiter *RateLimiter
+
 	err error
 }
 
 type Result struct {
 	statusCode int
 	status     string
+	header     http.Header
 	body       string
 	err        error
 	cookies    map[string]*http.Cookie
 }
 
+// StreamResult is the result of a streamed request.
+// The caller owns the Body and must close it when done.
+type StreamResult struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       io.ReadCloser
+}
+
 func NewRequest(client HTTPClient, verb string, baseURL *url.URL, apiPath string) *Request {
 	if len(apiPath) != 0 && !strings.HasPrefix(apiPath, "/") {
 		apiPath = path.Join("/", apiPath)
@@ -94,6 +120,30 @@ func (r *Request) Name(resourceName string) *Request {
 	return r
 }
 
+// SubResource sets the path of a sub-resource of the named resource, e.g. /targets/{uuid}/<subresources>.
+// Each sub-resource is a segment of the path, escaped if needed, e.g. a tag key with slashes.
+func (r *Request) SubResource(subresources ...string) *Request {
+	if r.err != nil {
+		return r
+	}
+	var segments []string
+	for _, subresource := range subresources {
+		segments = append(segments, url.PathEscape(subresource))
+	}
+	rawSubpath := path.Join(segments...)
+	subpath, _ := url.PathUnescape(rawSubpath)
+	if r.subpath != "" {
+		r.err = fmt.Errorf("Sub-resource has already been set to %s. Cannot be changed!", r.subpath)
+		return r
+	}
+	if len(subpath) == 0 {
+		r.err = errors.New("Sub-resource cannot be empty.")
+		return r
+	}
+	r.subpath, r.rawSubpath = subpath, rawSubpath
+	return r
+}
+
 // Set parameters for the request.
 func (r *Request) Param(paramName, value string) *Request {
 	if r.params == nil {
@@ -128,6 +178,55 @@ func (r *Request) BufferData(data *bytes.Buffer) *Request {
 	return r
 }
 
+// Body sets the request body from a reader, so that large payloads can be uploaded
+// without being held in memory.
+func (r *Request) Body(body io.Reader) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.data = body
+	return r
+}
+
+// Context sets the context used to cancel the request.
+func (r *Request) Context(ctx context.Context) *Request {
+	if r.err != nil {
+		return r
+	}
+	if ctx == nil {
+		r.err = errors.New("Context cannot be nil.")
+		return r
+	}
+	r.ctx = ctx
+	return r
+}
+
+// MaxResponseSize limits the number of bytes read from the response body by Do.
+// A value of 0 means no limit. It has no effect on Stream.
+func (r *Request) MaxResponseSize(size int64) *Request {
+	r.maxResponseSize = size
+	return r
+}
+
+// ResponseCache sets the cache of the listings of probes, targets and templates, which are served from
+// the cache and invalidated by the requests writing them.
+func (r *Request) ResponseCache(cache *ResponseCache) *Request {
+	r.cache = cache
+	return r
+}
+
+// NoCache makes the request reach the server even if its response is cached, and caches the response it receives.
+func (r *Request) NoCache() *Request {
+	r.noCache = true
+	return r
+}
+
+// RateLimiter sets the limiter the request waits for before being sent.
+func (r *Request) RateLimiter(limiter *RateLimiter) *Request {
+	r.limiter = limiter
+	return r
+}
+
 // URL returns the current working URL.
 func (r *Request) URL() *url.URL {
 	p := r.pathPrefix
@@ -140,11 +239,22 @@ func (r *Request) URL() *url.URL {
 		p = path.Join(p, r.resourceName)
 	}
 
+	var rawPath string
+	if r.rawSubpath != r.subpath {
+		// The sub-resource has escaped characters, i.e. slashes which do not separate the segments of the path
+		prefix := strings.TrimSuffix(p, "/")
+		rawPath = (&url.URL{Path: prefix}).EscapedPath() + "/" + r.rawSubpath
+		p = prefix + "/" + r.subpath
+	} else if len(r.subpath) != 0 {
+		p = path.Join(p, r.subpath)
+	}
+
 	finalURL := &url.URL{}
 	if r.baseURL != nil {
 		*finalURL = *r.baseURL
 	}
 	finalURL.Path = p
+	finalURL.RawPath = rawPath
 
 	query := url.Values{}
 	for key, values := range r.params {
@@ -159,30 +269,84 @@ func (r *Request) URL() *url.URL {
 }
 
 func (r *Request) Do() (Result, error) {
-	var result Result
-	err := r.request(func(resp *http.Response) {
-		result = parseHTTPResponse(resp)
-	})
+	defer r.invalidateCache()
+	lookup := r.cacheLookup()
+	if lookup != nil && lookup.fresh {
+		return lookup.entry.result, nil
+	}
+	resp, err := r.send()
 	if err != nil {
 		return Result{}, err
 	}
+	defer resp.Body.Close()
+	if lookup != nil && lookup.entry != nil && resp.StatusCode == http.StatusNotModified {
+		r.cache.revalidate(lookup)
+		return lookup.entry.result, nil
+	}
+	result := parseHTTPResponse(resp, r.maxResponseSize)
 	if result.err != nil {
-		return Result{}, err
+		return Result{}, result.err
+	}
+	if lookup != nil && result.statusCode == http.StatusOK {
+		r.cache.store(lookup, result)
 	}
 	return result, nil
 }
 
+// Stream performs the request and returns the response without reading the body,
+// which is useful for large payloads such as topology exports and entity listings.
+func (r *Request) Stream() (*StreamResult, error) {
+	defer r.invalidateCache()
+	lookup := r.cacheLookup()
+	if lookup != nil && lookup.fresh {
+		return lookup.entry.streamResult(), nil
+	}
+	resp, err := r.send()
+	if err != nil {
+		return nil, err
+	}
+	if lookup != nil && lookup.entry != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		r.cache.revalidate(lookup)
+		return lookup.entry.streamResult(), nil
+	}
+	body := resp.Body
+	if lookup != nil && resp.StatusCode == http.StatusOK {
+		body = &cachingBody{
+			ReadCloser: resp.Body,
+			cache:      r.cache,
+			lookup:     lookup,
+			result:     Result{statusCode: resp.StatusCode, status: resp.Status, header: resp.Header},
+		}
+	}
+	return &StreamResult{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
+
 // Perform the actual http request.
-// fn is the function to parse http Response.
-func (r *Request) request(fn func(*http.Response)) error {
+// The caller is responsible for closing the body of the returned response.
+func (r *Request) send() (*http.Response, error) {
 	if r.err != nil {
-		return r.err
+		return nil, r.err
 	}
 
 	requestURL := r.URL().String()
-	req, err := http.NewRequest(r.verb, requestURL, r.data)
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if r.limiter != nil {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, r.verb, requestURL, r.data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if r.headers != nil {
 		for key, value := range r.headers {
@@ -195,20 +359,32 @@ func (r *Request) request(fn func(*http.Response)) error {
 	//}
 
 	resp, err := r.client.Do(req)
-	if err != nil {
-		return err
+	if err == nil && r.limiter != nil {
+		r.limiter.observe(resp)
 	}
-
-	defer resp.Body.Close()
-	fn(resp)
-	return nil
+	return resp, err
 }
 
 func (r *Request) String() string {
 	return fmt.Sprintf("Request: %s %v Headers: %v", r.verb, r.URL(), r.headers)
 }
 
-func parseHTTPResponse(resp *http.Response) Result {
+// Close closes the response body.
+func (s *StreamResult) Close() error {
+	return s.Body.Close()
+}
+
+// Error reads the body of an unsuccessful response and builds an error from it.
+func (s *StreamResult) Error(requestDesc string) error {
+	defer s.Body.Close()
+	content, _ := ioutil.ReadAll(io.LimitReader(s.Body, maxErrorBodySize))
+	return buildStatusError(requestDesc, s.StatusCode, s.Status, string(content))
+}
+
+// maxErrorBodySize is the maximum number of bytes read from a streamed error response.
+const maxErrorBodySize = 64 * 1024
+
+func parseHTTPResponse(resp *http.Response, maxResponseSize int64) Result {
 	if resp == nil {
 		return Result{
 			err: errors.New("response sent in is nil"),
@@ -223,16 +399,27 @@ func parseHTTPResponse(resp *http.Response) Result {
 	}
 
 	// Parse response body
-	content, err := ioutil.ReadAll(resp.Body)
+	var reader io.Reader = resp.Body
+	if maxResponseSize > 0 {
+		// Read one more byte to detect a body exceeding the limit
+		reader = io.LimitReader(resp.Body, maxResponseSize+1)
+	}
+	content, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return Result{
 			err: fmt.Errorf("error reading response body: %v", err),
 		}
 	}
+	if maxResponseSize > 0 && int64(len(content)) > maxResponseSize {
+		return Result{
+			err: fmt.Errorf("response body exceeds the maximum size of %d bytes", maxResponseSize),
+		}
+	}
 
 	return Result{
 		statusCode: resp.StatusCode,
 		status:     resp.Status,
+		header:     resp.Header,
 		body:       string(content),
 		err:        nil,
 		cookies:    cookieMap,
@@ -2,10 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/turbonomic/turbo-api/pkg/api"
@@ -264,7 +268,7 @@ func TestParseHTTPResponse(t *testing.T) {
 		},
 	}
 	for _, item := range table {
-		result := parseHTTPResponse(item.resp)
+		result := parseHTTPResponse(item.resp, 0)
 		if item.expectsError && result.err == nil {
 			t.Error("Expected error, but got nil in err field in Result")
 		}
@@ -361,3 +365,125 @@ func TestRequest_Data(t *testing.T) {
 		}
 	}
 }
+
+func TestRequest_Body(t *testing.T) {
+	body := strings.NewReader("Some string")
+	u, _ := url.Parse("http://localhost")
+	request := NewRequest(http.DefaultClient, "POST", u, "").Body(body)
+	if request.data != body {
+		t.Errorf("Expected body %v, got %v", body, request.data)
+	}
+}
+
+func TestRequest_Context(t *testing.T) {
+	u, _ := url.Parse("http://localhost")
+	request := NewRequest(http.DefaultClient, "GET", u, "").Context(nil)
+	if request.err == nil {
+		t.Error("Expected error for nil context, but got no error.")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	if _, err := NewRequest(server.Client(), "GET", serverURL, "").Context(ctx).Do(); err == nil {
+		t.Error("Expected error for canceled context, but got no error.")
+	}
+}
+
+func TestRequest_DoMaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	table := []struct {
+		maxResponseSize int64
+		expectsError    bool
+	}{
+		{maxResponseSize: 0},
+		{maxResponseSize: 10},
+		{maxResponseSize: 9, expectsError: true},
+	}
+	for _, item := range table {
+		result, err := NewRequest(server.Client(), "GET", serverURL, "").
+			MaxResponseSize(item.maxResponseSize).Do()
+		if item.expectsError != (err != nil) {
+			t.Errorf("Max size %d: expects error %v, got %v", item.maxResponseSize, item.expectsError, err)
+		}
+		if !item.expectsError && result.body != "0123456789" {
+			t.Errorf("Max size %d: expected body %s, got %s", item.maxResponseSize, "0123456789", result.body)
+		}
+	}
+}
+
+func TestRequest_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Request-Body", string(content))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`[{"displayName":"foo"}]`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	// The max response size only applies to buffered responses
+	response, err := NewRequest(server.Client(), "POST", serverURL, "").
+		Body(strings.NewReader("upload")).MaxResponseSize(1).Stream()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer response.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, response.StatusCode)
+	}
+	if e, a := "upload", response.Header.Get("X-Request-Body"); e != a {
+		t.Errorf("Expected request body %s, got %s", e, a)
+	}
+	content, _ := ioutil.ReadAll(response.Body)
+	if e, a := `[{"displayName":"foo"}]`, string(content); e != a {
+		t.Errorf("Expected response body %s, got %s", e, a)
+	}
+}
+
+func TestStreamResult_Error(t *testing.T) {
+	response := &StreamResult{
+		StatusCode: 400,
+		Status:     "400 Bad Request",
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message":"some message"}`)),
+	}
+	expectedErr := &ResponseError{StatusCode: 400, message: "unsuccessful target addition response: 400 Bad Request. some message."}
+	if err := response.Error("target addition"); !reflect.DeepEqual(expectedErr, err) {
+		t.Errorf("Expected error %v, got %v", expectedErr, err)
+	}
+}
+
+func TestRequest_SubResourceURL(t *testing.T) {
+	u, _ := url.Parse("http://localhost")
+	tests := []struct {
+		subresources []string
+		expectStr    string
+		expectsError bool
+	}{
+		{[]string{"rediscover"}, "http://localhost/target/1/rediscover", false},
+		{[]string{"scenarios", "2"}, "http://localhost/target/1/scenarios/2", false},
+		{[]string{"tags", "app.kubernetes.io/name"}, "http://localhost/target/1/tags/app.kubernetes.io%2Fname", false},
+		{[]string{"tags", "a b/c"}, "http://localhost/target/1/tags/a%20b%2Fc", false},
+		{[]string{}, "", true},
+	}
+	for _, test := range tests {
+		r := NewRequest(http.DefaultClient, "GET", u, "").Resource(api.Resource_Type_Target).
+			Name("1").SubResource(test.subresources...)
+		if test.expectsError != (r.err != nil) {
+			t.Errorf("Sub-resources %v: expects error %v, got %v", test.subresources, test.expectsError, r.err)
+		}
+		if test.expectsError {
+			continue
+		}
+		if e, a := test.expectStr, r.URL().String(); e != a {
+			t.Errorf("expected %s, got %s", e, a)
+		}
+	}
+}
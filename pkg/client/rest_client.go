@@ -5,6 +5,8 @@ import (
 	"net/url"
 )
 
+// RESTClient builds requests to a Turbonomic service.
+// It must not be modified once built and is then safe for concurrent use by multiple goroutines.
 type RESTClient struct {
 	client *http.Client
 
@@ -12,6 +14,15 @@ type RESTClient struct {
 	apiPath string
 
 	basicAuth *BasicAuthentication
+
+	// The maximum number of bytes read from a buffered response body, 0 means no limit.
+	maxResponseSize int64
+
+	// The cache of the listings, if any
+	cache *ResponseCache
+
+	// The limiter of the rate of the requests, if any
+	limiter *RateLimiter
 }
 
 func NewRESTClient(client *http.Client, baseURL *url.URL, apiPath string) *RESTClient {
@@ -27,6 +38,25 @@ func (c *RESTClient) BasicAuthentication(ba *BasicAuthentication) *RESTClient {
 	return c
 }
 
+// MaxResponseSize limits the size of the buffered response body of all requests built by this client.
+func (c *RESTClient) MaxResponseSize(size int64) *RESTClient {
+	c.maxResponseSize = size
+	return c
+}
+
+// ResponseCache sets the cache of the listings of probes, targets and templates of all requests built by this client.
+// The cache can be shared by the clients of multiple services.
+func (c *RESTClient) ResponseCache(cache *ResponseCache) *RESTClient {
+	c.cache = cache
+	return c
+}
+
+// RateLimiter sets the limiter of the rate of all requests built by this client.
+func (c *RESTClient) RateLimiter(limiter *RateLimiter) *RESTClient {
+	c.limiter = limiter
+	return c
+}
+
 // Built request based on http verb and authentication.
 func (c *RESTClient) Verb(verb string) *Request {
 	request := NewRequest(c.client, verb, c.baseURL, c.apiPath)
@@ -34,6 +64,15 @@ func (c *RESTClient) Verb(verb string) *Request {
 	if c.basicAuth != nil {
 		request.BasicAuthentication(c.basicAuth)
 	}
+	if c.maxResponseSize > 0 {
+		request.MaxResponseSize(c.maxResponseSize)
+	}
+	if c.cache != nil {
+		request.ResponseCache(c.cache)
+	}
+	if c.limiter != nil {
+		request.RateLimiter(c.limiter)
+	}
 	return request
 }
 
@@ -10,10 +10,9 @@ import (
 func TestNewRESTClient(t *testing.T) {
 	baseURL, _ := url.Parse("http://localhost")
 	expectedRESTClient := &RESTClient{
-		http.DefaultClient,
-		baseURL,
-		"path/to/api",
-		nil,
+		client:  http.DefaultClient,
+		baseURL: baseURL,
+		apiPath: "path/to/api",
 	}
 	restClient := NewRESTClient(http.DefaultClient, baseURL, "path/to/api")
 	if !reflect.DeepEqual(restClient, expectedRESTClient) {
@@ -0,0 +1,266 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// filterTypePrefixes maps the entity types to the prefixes of the names of the filters searching them,
+// i.e. vms in vmsByName
+var filterTypePrefixes = map[api.EntityType]string{
+	api.EntityTypeVirtualMachine:  "vms",
+	api.EntityTypePhysicalMachine: "pms",
+	api.EntityTypeStorage:         "storage",
+	api.EntityTypeDataCenter:      "dataCenters",
+	api.EntityTypeContainer:       "containers",
+	api.EntityTypeContainerPod:    "containerpods",
+}
+
+// relatedFilterNames maps the entity types to their names in the filters searching entities by related entities,
+// i.e. PM in vmsByPMName
+var relatedFilterNames = map[api.EntityType]string{
+	api.EntityTypeVirtualMachine:  "VM",
+	api.EntityTypePhysicalMachine: "PM",
+	api.EntityTypeStorage:         "Storage",
+	api.EntityTypeDataCenter:      "DC",
+	api.EntityTypeContainerPod:    "Pod",
+}
+
+// SearchCriteria builds the criteria of a search of entities of a type.
+// All the criteria must be met by the entities found.
+// The first error met while building the criteria is returned by Build.
+type SearchCriteria struct {
+	request *api.SearchRequest
+	prefix  string
+	err     error
+}
+
+// NewSearchCriteria starts building the criteria of a search of entities of the given type
+func NewSearchCriteria(entityType api.EntityType) *SearchCriteria {
+	criteria := &SearchCriteria{
+		request: &api.SearchRequest{
+			ClassName:       string(entityType),
+			LogicalOperator: "AND",
+			CriteriaList:    []*api.FilterCriteria{},
+		},
+		prefix: filterTypePrefixes[entityType],
+	}
+	if entityType == "" {
+		criteria.err = errors.New("entity type of search cannot be empty")
+	}
+	return criteria
+}
+
+// NameRegex restricts the search to the entities with a display name matching the given regular expression,
+// ignoring case
+func (s *SearchCriteria) NameRegex(regex string) *SearchCriteria {
+	if regex == "" {
+		return s.fail(errors.New("name regex of search cannot be empty"))
+	}
+	return s.filter("ByName", api.FilterExpRegexMatch, regex)
+}
+
+// Tag restricts the search to the entities with the given tag key and any of the given values
+func (s *SearchCriteria) Tag(key string, values ...string) *SearchCriteria {
+	if key == "" || len(values) == 0 {
+		return s.fail(errors.New("tag of search must have a key and at least one value"))
+	}
+	var expVals []string
+	for _, value := range values {
+		expVals = append(expVals, key+"="+value)
+	}
+	return s.filter("ByTag", api.FilterExpEquals, strings.Join(expVals, "|"))
+}
+
+// State restricts the search to the entities in the given state, i.e. ACTIVE, IDLE, SUSPEND
+func (s *SearchCriteria) State(state string) *SearchCriteria {
+	if state == "" {
+		return s.fail(errors.New("state of search cannot be empty"))
+	}
+	return s.filter("ByState", api.FilterExpEquals, state)
+}
+
+// Environment restricts the search to the entities in the given environment, i.e. CLOUD or ONPREM
+func (s *SearchCriteria) Environment(environmentType string) *SearchCriteria {
+	if s.err != nil {
+		return s
+	}
+	s.request.EnvironmentType = environmentType
+	return s
+}
+
+// RelatedTo restricts the search to the entities related to entities of the given type
+// with a display name matching the given regular expression, i.e. the VMs hosted by PMs named ^esx.*
+func (s *SearchCriteria) RelatedTo(entityType api.EntityType, nameRegex string) *SearchCriteria {
+	related, found := relatedFilterNames[entityType]
+	if !found {
+		return s.fail(fmt.Errorf("search by related entities of type %s is not supported", entityType))
+	}
+	return s.filter("By"+related+"Name", api.FilterExpRegexMatch, nameRegex)
+}
+
+// InGroup restricts the search to the members of any of the groups with the given UUIDs
+func (s *SearchCriteria) InGroup(groupUUIDs ...string) *SearchCriteria {
+	if s.err != nil {
+		return s
+	}
+	if len(groupUUIDs) == 0 {
+		return s.fail(errors.New("groups of search cannot be empty"))
+	}
+	s.request.Scope = append(s.request.Scope, groupUUIDs...)
+	return s
+}
+
+// Filter adds a criterion the builder has no method for, i.e. {"filterType":"vmsByCluster", ...}
+func (s *SearchCriteria) Filter(criteria *api.FilterCriteria) *SearchCriteria {
+	if s.err != nil {
+		return s
+	}
+	s.request.CriteriaList = append(s.request.CriteriaList, criteria)
+	return s
+}
+
+// Build returns the search request the criteria compile to
+func (s *SearchCriteria) Build() (*api.SearchRequest, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.request, nil
+}
+
+func (s *SearchCriteria) filter(filterName, expType, expVal string) *SearchCriteria {
+	if s.err != nil {
+		return s
+	}
+	if s.prefix == "" {
+		return s.fail(fmt.Errorf("search filters are not supported for entity type %s", s.request.ClassName))
+	}
+	return s.Filter(&api.FilterCriteria{FilterType: s.prefix + filterName, ExpType: expType, ExpVal: expVal})
+}
+
+func (s *SearchCriteria) fail(err error) *SearchCriteria {
+	if s.err == nil {
+		s.err = err
+	}
+	return s
+}
+
+// SearchOptions configures the order and the pagination of search results
+type SearchOptions struct {
+	// Field to order the results by, i.e. NAME, SEVERITY, UTILIZATION
+	OrderBy   string
+	Ascending bool
+	// Number of results per page, defaults to the server default
+	PageSize int
+	// Maximum number of results returned by Search, 0 means no limit
+	MaxResults int
+}
+
+// SearchResultPage is a page of search results
+type SearchResultPage struct {
+	Entities []api.ServiceEntity
+	// Cursor of the next page, empty for the last page
+	NextCursor string
+	// Total number of results of the search, if reported by the server
+	TotalCount int
+}
+
+// SearchClient searches entities via api service, following the pages of results.
+type SearchClient struct {
+	api *APIClient
+}
+
+// NewSearchClient builds a client searching entities with the session of the given api service client
+func NewSearchClient(apiClient *APIClient) *SearchClient {
+	return &SearchClient{api: apiClient}
+}
+
+// Search returns the entities meeting the given criteria, following the pages of results
+func (c *SearchClient) Search(criteria *SearchCriteria, opts *SearchOptions) ([]api.ServiceEntity, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	newRequest, err := c.searchRequest(criteria, opts)
+	if err != nil {
+		return nil, err
+	}
+	var entities []api.ServiceEntity
+	if err := c.api.streamList("search", newRequest, func(decoder *JSONArrayDecoder) error {
+		if opts.MaxResults > 0 && len(entities) >= opts.MaxResults {
+			return errStopList
+		}
+		var entity api.ServiceEntity
+		if err := decoder.Decode(&entity); err != nil {
+			return err
+		}
+		entities = append(entities, entity)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// SearchPage returns the page of entities meeting the given criteria at the given cursor,
+// which is empty for the first page
+func (c *SearchClient) SearchPage(criteria *SearchCriteria, opts *SearchOptions, cursor string) (*SearchResultPage, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	newRequest, err := c.searchRequest(criteria, opts)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.api.doWithSession(func() *Request {
+		request := newRequest().Header("Accept", "application/json")
+		if cursor != "" {
+			request.Param("cursor", cursor)
+		}
+		return request
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search request failed: %v", err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("search", response.statusCode, response.status, response.body)
+	}
+	page := &SearchResultPage{NextCursor: response.header.Get("X-Next-Cursor")}
+	if total := response.header.Get("X-Total-Record-Count"); total != "" {
+		if page.TotalCount, err = strconv.Atoi(total); err != nil {
+			return nil, fmt.Errorf("invalid total record count %q of search response", total)
+		}
+	}
+	if err := json.Unmarshal([]byte(response.body), &page.Entities); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall search response: %v", err)
+	}
+	return page, nil
+}
+
+// searchRequest builds the criteria into the body of the search request and the options into its parameters
+func (c *SearchClient) searchRequest(criteria *SearchCriteria, opts *SearchOptions) (func() *Request, error) {
+	searchRequest, err := criteria.Build()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall search criteria: %v", err)
+	}
+	return func() *Request {
+		request := c.api.Post().Resource(api.Resource_Type_Search).
+			Header("Content-Type", "application/json").
+			Data(data)
+		if opts.OrderBy != "" {
+			request.Param("order_by", opts.OrderBy).Param("ascending", strconv.FormatBool(opts.Ascending))
+		}
+		if opts.PageSize > 0 {
+			request.Param("limit", strconv.Itoa(opts.PageSize))
+		}
+		return request
+	}, nil
+}
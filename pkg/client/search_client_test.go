@@ -0,0 +1,155 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestSearchCriteria_Build(t *testing.T) {
+	table := []struct {
+		criteria *SearchCriteria
+
+		expectedRequest *api.SearchRequest
+		expectsError    bool
+	}{
+		{
+			// Entity type only
+			criteria: NewSearchCriteria(api.EntityTypeVirtualMachine),
+
+			expectedRequest: &api.SearchRequest{ClassName: "VirtualMachine", LogicalOperator: "AND",
+				CriteriaList: []*api.FilterCriteria{}},
+		},
+		{
+			// All criteria
+			criteria: NewSearchCriteria(api.EntityTypeVirtualMachine).
+				NameRegex("^web-.*").
+				Tag("env", "prod", "staging").
+				State("ACTIVE").
+				Environment(api.EnvironmentTypeCloud).
+				RelatedTo(api.EntityTypePhysicalMachine, "^esx.*").
+				InGroup("group-1", "group-2").
+				Filter(&api.FilterCriteria{FilterType: "vmsByCluster", ExpType: api.FilterExpEquals, ExpVal: "c1"}),
+
+			expectedRequest: &api.SearchRequest{
+				ClassName:       "VirtualMachine",
+				LogicalOperator: "AND",
+				CriteriaList: []*api.FilterCriteria{
+					{FilterType: "vmsByName", ExpType: "RXEQ", ExpVal: "^web-.*"},
+					{FilterType: "vmsByTag", ExpType: "EQ", ExpVal: "env=prod|env=staging"},
+					{FilterType: "vmsByState", ExpType: "EQ", ExpVal: "ACTIVE"},
+					{FilterType: "vmsByPMName", ExpType: "RXEQ", ExpVal: "^esx.*"},
+					{FilterType: "vmsByCluster", ExpType: "EQ", ExpVal: "c1"},
+				},
+				Scope:           []string{"group-1", "group-2"},
+				EnvironmentType: "CLOUD",
+			},
+		},
+		{
+			// Empty entity type
+			criteria: NewSearchCriteria("").InGroup("group-1"),
+
+			expectsError: true,
+		},
+		{
+			// Unsupported entity type
+			criteria: NewSearchCriteria("BusinessUser").NameRegex("foo"),
+
+			expectsError: true,
+		},
+		{
+			// Unsupported related entity type
+			criteria: NewSearchCriteria(api.EntityTypeVirtualMachine).RelatedTo(api.EntityTypeContainer, "foo"),
+
+			expectsError: true,
+		},
+		{
+			// Tag without value
+			criteria: NewSearchCriteria(api.EntityTypeVirtualMachine).Tag("env").NameRegex("foo"),
+
+			expectsError: true,
+		},
+	}
+	for _, item := range table {
+		request, err := item.criteria.Build()
+		if err != nil {
+			if !item.expectsError {
+				t.Errorf("Unexpected error %v", err)
+			}
+			continue
+		}
+		if item.expectsError {
+			t.Errorf("Expected an error, got %+v", request)
+		} else if !reflect.DeepEqual(item.expectedRequest, request) {
+			t.Errorf("Expected %+v, got %+v", item.expectedRequest, request)
+		}
+	}
+}
+
+func newTestSearchClient(t *testing.T) (*SearchClient, func()) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var request api.SearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "vmsByName", request.CriteriaList[0].FilterType)
+		assert.Equal(t, "NAME", r.URL.Query().Get("order_by"))
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		w.Header().Set("X-Total-Record-Count", "5")
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("X-Next-Cursor", "2")
+			fmt.Fprint(w, `[{"uuid":"0"},{"uuid":"1"}]`)
+		case "2":
+			w.Header().Set("X-Next-Cursor", "4")
+			fmt.Fprint(w, `[{"uuid":"2"},{"uuid":"3"}]`)
+		default:
+			fmt.Fprint(w, `[{"uuid":"4"}]`)
+		}
+	}, "search")
+	return NewSearchClient(apiClient), server.Close
+}
+
+func TestSearchClient_Search(t *testing.T) {
+	searchClient, closeServer := newTestSearchClient(t)
+	defer closeServer()
+
+	criteria := NewSearchCriteria(api.EntityTypeVirtualMachine).NameRegex("web")
+	entities, err := searchClient.Search(criteria, &SearchOptions{OrderBy: "NAME", Ascending: true, PageSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(entities))
+
+	entities, err = searchClient.Search(criteria, &SearchOptions{OrderBy: "NAME", PageSize: 2, MaxResults: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []api.ServiceEntity{{UUID: "0"}, {UUID: "1"}, {UUID: "2"}}, entities)
+
+	_, err = searchClient.Search(NewSearchCriteria(""), nil)
+	assert.Error(t, err)
+}
+
+func TestSearchClient_SearchPage(t *testing.T) {
+	searchClient, closeServer := newTestSearchClient(t)
+	defer closeServer()
+
+	criteria := NewSearchCriteria(api.EntityTypeVirtualMachine).NameRegex("web")
+	opts := &SearchOptions{OrderBy: "NAME", PageSize: 2}
+	page, err := searchClient.SearchPage(criteria, opts, "")
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchResultPage{
+		Entities:   []api.ServiceEntity{{UUID: "0"}, {UUID: "1"}},
+		NextCursor: "2",
+		TotalCount: 5,
+	}, page)
+
+	page, err = searchClient.SearchPage(criteria, opts, "4")
+	assert.NoError(t, err)
+	assert.Equal(t, "", page.NextCursor)
+	assert.Equal(t, []api.ServiceEntity{{UUID: "4"}}, page.Entities)
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// statSeriesCSVHeader is the header of the CSV export of statistics time series
+var statSeriesCSVHeader = []string{
+	"name", "units", "relatedEntityType", "group", "epoch", "date", "value", "avg", "min", "max", "capacity",
+}
+
+// StatSeries is the time series of a statistic, for a group of values if the statistic is grouped
+type StatSeries struct {
+	Name              string
+	Units             string
+	RelatedEntityType string
+	// Filters identifying the group of the series, if the statistic is grouped
+	Filters []*api.StatFilter
+	// Points of the series in the order of the snapshots
+	Points []StatPoint
+}
+
+// StatPoint is the value of a statistic at a point in time
+type StatPoint struct {
+	Time time.Time
+	// Whether the value is historical, current or projected
+	Epoch string
+	Value float64
+	Avg   float64
+	Min   float64
+	Max   float64
+	// Total capacity of the group of values
+	Capacity float64
+}
+
+// Group returns the filters identifying the group of the series as type=value pairs separated by semicolons
+func (s *StatSeries) Group() string {
+	var group []string
+	for _, filter := range s.Filters {
+		group = append(group, filter.Type+"="+filter.Value)
+	}
+	return strings.Join(group, ";")
+}
+
+// NewStatSeries splits the given snapshots into a time series per statistic and group
+func NewStatSeries(snapshots []api.StatSnapshot) ([]*StatSeries, error) {
+	var series []*StatSeries
+	seriesByKey := make(map[string]*StatSeries)
+	for _, snapshot := range snapshots {
+		date, err := parseStatDate(snapshot.Date)
+		if err != nil {
+			return nil, err
+		}
+		for _, stat := range snapshot.Statistics {
+			s := &StatSeries{
+				Name:              stat.Name,
+				Units:             stat.Units,
+				RelatedEntityType: stat.RelatedEntityType,
+				Filters:           stat.Filters,
+			}
+			key := strings.Join([]string{s.Name, s.RelatedEntityType, s.Group()}, "|")
+			if existing, found := seriesByKey[key]; found {
+				s = existing
+			} else {
+				seriesByKey[key] = s
+				series = append(series, s)
+			}
+			point := StatPoint{Time: date, Epoch: snapshot.Epoch, Value: stat.Value}
+			if stat.Values != nil {
+				point.Avg, point.Min, point.Max = stat.Values.Avg, stat.Values.Min, stat.Values.Max
+			}
+			if stat.Capacity != nil {
+				point.Capacity = stat.Capacity.Total
+			}
+			s.Points = append(s.Points, point)
+		}
+	}
+	return series, nil
+}
+
+// WriteStatSeriesCSV writes the given time series as CSV, one row per point
+func WriteStatSeriesCSV(w io.Writer, series []*StatSeries) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(statSeriesCSVHeader); err != nil {
+		return err
+	}
+	for _, s := range series {
+		for _, point := range s.Points {
+			if err := writer.Write([]string{
+				s.Name, s.Units, s.RelatedEntityType, s.Group(), point.Epoch, point.Time.UTC().Format(time.RFC3339),
+				formatStatValue(point.Value), formatStatValue(point.Avg), formatStatValue(point.Min),
+				formatStatValue(point.Max), formatStatValue(point.Capacity),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseStatDate parses the date of a snapshot, in ISO 8601 format or in milliseconds since epoch
+func parseStatDate(date string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return t, nil
+	}
+	millis, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid statistics date %q", date)
+	}
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC(), nil
+}
+
+func formatStatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
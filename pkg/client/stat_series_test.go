@@ -0,0 +1,36 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestNewStatSeries_InvalidDate(t *testing.T) {
+	_, err := NewStatSeries([]api.StatSnapshot{{Date: "yesterday"}})
+	assert.EqualError(t, err, `invalid statistics date "yesterday"`)
+}
+
+func TestWriteStatSeriesCSV(t *testing.T) {
+	series, err := NewStatSeries([]api.StatSnapshot{
+		{Date: "2020-01-01T00:00:00+01:00", Epoch: "HISTORICAL", Statistics: []*api.Stat{
+			{Name: "costPrice", Units: "$/h", Value: 0.25, RelatedEntityType: "VirtualMachine",
+				Filters: []*api.StatFilter{{Type: "cloudService", Value: "EC2"}, {Type: "region", Value: "us-east, 1"}}},
+		}},
+		{Date: "2020-01-02T00:00:00Z", Epoch: "PROJECTED", Statistics: []*api.Stat{
+			{Name: "costPrice", Units: "$/h", Value: 0.5, RelatedEntityType: "VirtualMachine",
+				Filters: []*api.StatFilter{{Type: "cloudService", Value: "EC2"}, {Type: "region", Value: "us-east, 1"}},
+				Values:  &api.StatValues{Avg: 0.5, Min: 0.1, Max: 1}},
+		}},
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteStatSeriesCSV(&buf, series))
+	assert.Equal(t, "name,units,relatedEntityType,group,epoch,date,value,avg,min,max,capacity\n"+
+		`costPrice,$/h,VirtualMachine,"cloudService=EC2;region=us-east, 1",HISTORICAL,2019-12-31T23:00:00Z,0.25,0,0,0,0`+"\n"+
+		`costPrice,$/h,VirtualMachine,"cloudService=EC2;region=us-east, 1",PROJECTED,2020-01-02T00:00:00Z,0.5,0.5,0.1,1,0`+"\n",
+		buf.String())
+}
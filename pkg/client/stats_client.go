@@ -0,0 +1,86 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// StatsClient queries the statistics of entities, groups and markets via api service, i.e. utilization and cost.
+type StatsClient struct {
+	api *APIClient
+}
+
+// StatQuery describes the statistics to query and the period to query them over
+type StatQuery struct {
+	// Start and end of the period, built with StatTime or RelativeStatTime.
+	// The current statistics are returned if both are empty.
+	StartDate string
+	EndDate   string
+	// Names of the statistics, i.e. VCPU, VMem, costPrice
+	StatNames []string
+	// Type of the related entities the statistics are aggregated from, i.e. VirtualMachine
+	RelatedEntityType string
+	// Criteria to group the statistics by, i.e. key, virtualDisk, businessUnit
+	GroupBy []string
+	Filters []*api.StatFilter
+}
+
+// NewStatsClient builds a client querying statistics with the session of the given api service client
+func NewStatsClient(apiClient *APIClient) *StatsClient {
+	return &StatsClient{api: apiClient}
+}
+
+// StatTime formats the given time as the start or end date of a statistics query
+func StatTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// RelativeStatTime formats the given offset from the time of the query as the start or end date
+// of a statistics query, i.e. -1d for a day ago and +12h for in 12 hours, which projected statistics
+// are returned for. The offset is rounded to hours.
+func RelativeStatTime(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	hours := int64(offset.Round(time.Hour) / time.Hour)
+	if hours != 0 && hours%24 == 0 {
+		return fmt.Sprintf("%s%dd", sign, hours/24)
+	}
+	return fmt.Sprintf("%s%dh", sign, hours)
+}
+
+// GetStats gets the statistics of the entity, group or market with the given UUID
+func (c *StatsClient) GetStats(uuid string, query *StatQuery) ([]api.StatSnapshot, error) {
+	if query == nil {
+		query = &StatQuery{}
+	}
+	input := &api.StatPeriodInput{StartDate: query.StartDate, EndDate: query.EndDate}
+	for _, name := range query.StatNames {
+		input.Statistics = append(input.Statistics, &api.StatInput{
+			Name:              name,
+			RelatedEntityType: query.RelatedEntityType,
+			GroupBy:           query.GroupBy,
+			Filters:           query.Filters,
+		})
+	}
+	var snapshots []api.StatSnapshot
+	if err := c.api.doJSON("get stats", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Stats).Name(uuid)
+	}, input, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetStatSeries gets the statistics of the entity, group or market with the given UUID as time series
+func (c *StatsClient) GetStatSeries(uuid string, query *StatQuery) ([]*StatSeries, error) {
+	snapshots, err := c.GetStats(uuid, query)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatSeries(snapshots)
+}
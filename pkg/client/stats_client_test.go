@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestRelativeStatTime(t *testing.T) {
+	table := []struct {
+		offset time.Duration
+
+		expectedTime string
+	}{
+		{-24 * time.Hour, "-1d"},
+		{-36 * time.Hour, "-36h"},
+		{7 * 24 * time.Hour, "+7d"},
+		{90 * time.Minute, "+2h"},
+		{0, "+0h"},
+	}
+	for _, item := range table {
+		assert.Equal(t, item.expectedTime, RelativeStatTime(item.offset), item.offset.String())
+	}
+}
+
+func TestStatTime(t *testing.T) {
+	assert.Equal(t, "1577836800000", StatTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestStatsClient_GetStatSeries(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/stats/vm-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var input api.StatPeriodInput
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+		assert.Equal(t, api.StatPeriodInput{
+			StartDate: "-1d",
+			EndDate:   "+0h",
+			Statistics: []*api.StatInput{
+				{Name: "StorageAmount", GroupBy: []string{"key"}},
+				{Name: "VCPU", GroupBy: []string{"key"}},
+			},
+		}, input)
+		fmt.Fprint(w, `[
+{"date":"2020-01-01T00:00:00Z","epoch":"HISTORICAL","statistics":[
+ {"name":"VCPU","units":"MHz","value":100,"values":{"avg":100,"max":150,"min":50,"total":100},"capacity":{"total":2000}},
+ {"name":"StorageAmount","units":"MB","value":10,"filters":[{"type":"key","value":"disk-1"}]},
+ {"name":"StorageAmount","units":"MB","value":20,"filters":[{"type":"key","value":"disk-2"}]}]},
+{"date":"1577840400000","epoch":"CURRENT","statistics":[
+ {"name":"VCPU","units":"MHz","value":200.5,"capacity":{"total":2000}},
+ {"name":"StorageAmount","units":"MB","value":11,"filters":[{"type":"key","value":"disk-1"}]}]}]`)
+	}, "stats")
+	defer server.Close()
+	statsClient := NewStatsClient(apiClient)
+
+	series, err := statsClient.GetStatSeries("vm-1", &StatQuery{
+		StartDate: RelativeStatTime(-24 * time.Hour),
+		EndDate:   RelativeStatTime(0),
+		StatNames: []string{"StorageAmount", "VCPU"},
+		GroupBy:   []string{"key"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(series))
+	assert.Equal(t, "VCPU", series[0].Name)
+	assert.Equal(t, []StatPoint{
+		{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Epoch: "HISTORICAL", Value: 100, Avg: 100, Min: 50, Max: 150, Capacity: 2000},
+		{Time: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), Epoch: "CURRENT", Value: 200.5, Capacity: 2000},
+	}, series[0].Points)
+	assert.Equal(t, "key=disk-1", series[1].Group())
+	assert.Equal(t, 2, len(series[1].Points))
+	assert.Equal(t, "key=disk-2", series[2].Group())
+	assert.Equal(t, 1, len(series[2].Points))
+
+	_, err = statsClient.GetStats("vm-2", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// TagClient manages the tags of entities and groups via api service, and finds entities by tag.
+type TagClient struct {
+	api *APIClient
+}
+
+// NewTagClient builds a client managing tags with the session of the given api service client
+func NewTagClient(apiClient *APIClient) *TagClient {
+	return &TagClient{api: apiClient}
+}
+
+// ListTags lists all the tag keys together with their values
+func (c *TagClient) ListTags() ([]api.Tag, error) {
+	var tags []api.Tag
+	if err := c.api.doJSON("list tags", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Tags)
+	}, nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListTagKeys lists all the tag keys
+func (c *TagClient) ListTagKeys() ([]string, error) {
+	tags, err := c.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, tag := range tags {
+		keys = append(keys, tag.Key)
+	}
+	return keys, nil
+}
+
+// GetEntityTags gets the tags of the entity with the given UUID
+func (c *TagClient) GetEntityTags(uuid string) ([]api.Tag, error) {
+	return c.getTags("get entity tags", api.Resource_Type_Entities, uuid)
+}
+
+// GetGroupTags gets the tags of the group with the given UUID
+func (c *TagClient) GetGroupTags(uuid string) ([]api.Tag, error) {
+	return c.getTags("get group tags", api.Resource_Type_Groups, uuid)
+}
+
+// AddEntityTags adds the given tags to the entity with the given UUID and returns the tags of the entity
+func (c *TagClient) AddEntityTags(uuid string, tags ...api.Tag) ([]api.Tag, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tag to add to entity %s", uuid)
+	}
+	var updated []api.Tag
+	if err := c.api.doJSON("add entity tags", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Entities).Name(uuid).SubResource(string(api.Resource_Type_Tags))
+	}, tags, &updated); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully added %d tags to entity %v.", len(tags), uuid)
+	return updated, nil
+}
+
+// RemoveEntityTag removes the tag with the given key, which may have slashes, from the entity with the given UUID
+func (c *TagClient) RemoveEntityTag(uuid, key string) error {
+	if err := c.api.doJSON("remove entity tag", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Entities).Name(uuid).
+			SubResource(string(api.Resource_Type_Tags), key)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully removed tag %v from entity %v.", key, uuid)
+	return nil
+}
+
+// RemoveEntityTags removes all the tags from the entity with the given UUID
+func (c *TagClient) RemoveEntityTags(uuid string) error {
+	if err := c.api.doJSON("remove entity tags", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Entities).Name(uuid).SubResource(string(api.Resource_Type_Tags))
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully removed all the tags from entity %v.", uuid)
+	return nil
+}
+
+// FindEntitiesByTag finds the entities of the given type tagged with the given key and any of the given values
+func (c *TagClient) FindEntitiesByTag(entityType api.EntityType, key string, values ...string) ([]api.ServiceEntity, error) {
+	return NewSearchClient(c.api).Search(NewSearchCriteria(entityType).Tag(key, values...), nil)
+}
+
+func (c *TagClient) getTags(requestDesc string, resource api.ResourceType, uuid string) ([]api.Tag, error) {
+	var tags []api.Tag
+	if err := c.api.doJSON(requestDesc, func() *Request {
+		return c.api.Get().Resource(resource).Name(uuid).SubResource(string(api.Resource_Type_Tags))
+	}, nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestTagClient(t *testing.T) {
+	var lock sync.Mutex
+	entityTags := map[string][]string{"env": {"prod"}}
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /tags":
+			fmt.Fprint(w, `[{"key":"env","values":["prod","staging"]},{"key":"owner","values":["team-a"]}]`)
+		case "GET /entities/vm-1/tags":
+			var tags []api.Tag
+			for _, key := range []string{"env", "owner", "app.kubernetes.io/name"} {
+				if values, found := entityTags[key]; found {
+					tags = append(tags, api.Tag{Key: key, Values: values})
+				}
+			}
+			json.NewEncoder(w).Encode(tags)
+		case "POST /entities/vm-1/tags":
+			var tags []api.Tag
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&tags))
+			for _, tag := range tags {
+				entityTags[tag.Key] = append(entityTags[tag.Key], tag.Values...)
+			}
+			json.NewEncoder(w).Encode(tags)
+		case "DELETE /entities/vm-1/tags/owner":
+			delete(entityTags, "owner")
+		case "DELETE /entities/vm-1/tags/app.kubernetes.io/name":
+			// The slash of the key is escaped
+			if !strings.HasSuffix(r.RequestURI, "/entities/vm-1/tags/app.kubernetes.io%2Fname") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(entityTags, "app.kubernetes.io/name")
+		case "DELETE /entities/vm-1/tags":
+			entityTags = map[string][]string{}
+		case "GET /groups/group-1/tags":
+			fmt.Fprint(w, `[{"key":"owner","values":["team-b"]}]`)
+		case "POST /search":
+			var request api.SearchRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, []*api.FilterCriteria{{FilterType: "vmsByTag", ExpType: "EQ", ExpVal: "env=prod"}},
+				request.CriteriaList)
+			fmt.Fprint(w, `[{"uuid":"vm-1","className":"VirtualMachine","tags":{"env":["prod"]}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "tags", "entities", "groups", "search")
+	defer server.Close()
+	tagClient := NewTagClient(apiClient)
+
+	keys, err := tagClient.ListTagKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"env", "owner"}, keys)
+
+	_, err = tagClient.AddEntityTags("vm-1")
+	assert.Error(t, err)
+	_, err = tagClient.AddEntityTags("vm-1", api.Tag{Key: "owner", Values: []string{"team-a"}})
+	assert.NoError(t, err)
+	tags, err := tagClient.GetEntityTags("vm-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Tag{{Key: "env", Values: []string{"prod"}}, {Key: "owner", Values: []string{"team-a"}}}, tags)
+
+	assert.NoError(t, tagClient.RemoveEntityTag("vm-1", "owner"))
+	tags, err = tagClient.GetEntityTags("vm-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Tag{{Key: "env", Values: []string{"prod"}}}, tags)
+
+	_, err = tagClient.AddEntityTags("vm-1", api.Tag{Key: "app.kubernetes.io/name", Values: []string{"nginx"}})
+	assert.NoError(t, err)
+	assert.NoError(t, tagClient.RemoveEntityTag("vm-1", "app.kubernetes.io/name"))
+	tags, err = tagClient.GetEntityTags("vm-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Tag{{Key: "env", Values: []string{"prod"}}}, tags)
+
+	tags, err = tagClient.GetGroupTags("group-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Tag{{Key: "owner", Values: []string{"team-b"}}}, tags)
+
+	entities, err := tagClient.FindEntitiesByTag(api.EntityTypeVirtualMachine, "env", "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.ServiceEntity{{UUID: "vm-1", ClassName: "VirtualMachine",
+		Tags: map[string][]string{"env": {"prod"}}}}, entities)
+
+	assert.NoError(t, tagClient.RemoveEntityTags("vm-1"))
+	tags, err = tagClient.GetEntityTags("vm-1")
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+	_, err = tagClient.GetGroupTags("group-2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// MaskedSecretValue is the value api service reports for secret input fields.
+// A desired secret input field set to it keeps the secret of the existing target.
+const MaskedSecretValue = "*****"
+
+// TargetOutcome is the outcome of adding a target which may already exist
+type TargetOutcome string
+
+const (
+	// The target did not exist and was created
+	TargetCreated TargetOutcome = "Created"
+	// The target existed and its input fields were updated
+	TargetUpdated TargetOutcome = "Updated"
+	// The target existed with the same input fields and was left untouched
+	TargetUnchanged TargetOutcome = "Unchanged"
+	// The target existed with another probe type, and was deleted and created again
+	TargetRecreated TargetOutcome = "Recreated"
+	// The target existed with the same input fields except for secret fields masked by the server, and was left
+	// untouched as configured, so that a changed secret was not applied
+	TargetSecretsUnverified TargetOutcome = "SecretsUnverified"
+)
+
+// InputFieldDiff lists the input fields of a desired target differing from the ones of the existing target
+type InputFieldDiff struct {
+	// Names of the fields with a different value, or missing from the existing target
+	Changed []string
+	// Names of the secret fields masked by the server, whose value cannot be compared
+	MaskedSecrets []string
+}
+
+// Empty returns true if the input fields of the existing target are the desired ones. The secret fields masked by
+// the server cannot be compared, so they may have changed and the diff is not empty.
+func (d *InputFieldDiff) Empty() bool {
+	return len(d.Changed) == 0 && len(d.MaskedSecrets) == 0
+}
+
+// DiffInputFields compares the input fields of an existing target with the desired ones.
+// The fields which are not desired are not compared, since the server reports the fields left to their default.
+// A desired secret field set to MaskedSecretValue is unchanged, and an existing secret field masked by the server
+// is reported as masked otherwise, since it cannot be compared.
+func DiffInputFields(existing, desired []*api.InputField) *InputFieldDiff {
+	existingFields := make(map[string]*api.InputField, len(existing))
+	for _, field := range existing {
+		existingFields[field.Name] = field
+	}
+	diff := &InputFieldDiff{}
+	for _, field := range desired {
+		existingField, found := existingFields[field.Name]
+		switch {
+		case !found:
+			diff.Changed = append(diff.Changed, field.Name)
+		case existingField.Value == field.Value:
+		case field.IsSecret && field.Value == MaskedSecretValue:
+		case existingField.Value == MaskedSecretValue && (existingField.IsSecret || field.IsSecret):
+			diff.MaskedSecrets = append(diff.MaskedSecrets, field.Name)
+		default:
+			diff.Changed = append(diff.Changed, field.Name)
+		}
+	}
+	return diff
+}
+
+// keepMaskedSecrets returns the desired input fields with the secret fields set to MaskedSecretValue
+// replaced by the existing ones
+func keepMaskedSecrets(existing, desired []*api.InputField) []*api.InputField {
+	existingFields := make(map[string]*api.InputField, len(existing))
+	for _, field := range existing {
+		existingFields[field.Name] = field
+	}
+	var inputFields []*api.InputField
+	for _, field := range desired {
+		if existingField, found := existingFields[field.Name]; found && field.IsSecret && field.Value == MaskedSecretValue {
+			field = existingField
+		}
+		inputFields = append(inputFields, field)
+	}
+	return inputFields
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestDiffInputFields(t *testing.T) {
+	existing := []*api.InputField{
+		{Name: "targetIdentifier", Value: "cluster"},
+		{Name: "username", Value: "admin"},
+		{Name: "password", Value: MaskedSecretValue, IsSecret: true},
+		{Name: "token", Value: "t1", IsSecret: true},
+		{Name: "port", Value: "443"},
+	}
+	table := []struct {
+		desired []*api.InputField
+
+		expectedDiff *InputFieldDiff
+	}{
+		{
+			// Unchanged
+			desired: []*api.InputField{
+				{Name: "targetIdentifier", Value: "cluster"},
+				{Name: "username", Value: "admin"},
+				{Name: "password", Value: MaskedSecretValue, IsSecret: true},
+				{Name: "token", Value: "t1", IsSecret: true},
+			},
+
+			expectedDiff: &InputFieldDiff{},
+		},
+		{
+			// Changed
+			desired: []*api.InputField{
+				{Name: "targetIdentifier", Value: "cluster"},
+				{Name: "username", Value: "root"},
+				{Name: "token", Value: "t2", IsSecret: true},
+				{Name: "proxy", Value: "proxy:3128"},
+			},
+
+			expectedDiff: &InputFieldDiff{Changed: []string{"username", "token", "proxy"}},
+		},
+		{
+			// Masked secret
+			desired: []*api.InputField{
+				{Name: "password", Value: "secret", IsSecret: true},
+				{Name: "token", Value: MaskedSecretValue, IsSecret: true},
+			},
+
+			expectedDiff: &InputFieldDiff{MaskedSecrets: []string{"password"}},
+		},
+		{
+			// Not a secret
+			desired: []*api.InputField{{Name: "port", Value: MaskedSecretValue}},
+
+			expectedDiff: &InputFieldDiff{Changed: []string{"port"}},
+		},
+	}
+	for _, item := range table {
+		diff := DiffInputFields(existing, item.desired)
+		assert.Equal(t, item.expectedDiff, diff)
+		assert.Equal(t, len(item.expectedDiff.Changed)+len(item.expectedDiff.MaskedSecrets) == 0, diff.Empty())
+	}
+}
+
+func TestKeepMaskedSecrets(t *testing.T) {
+	existing := []*api.InputField{
+		{Name: "username", Value: "admin"},
+		{Name: "password", Value: "p1", IsSecret: true},
+	}
+	desired := []*api.InputField{
+		{Name: "username", Value: "root"},
+		{Name: "password", Value: MaskedSecretValue, IsSecret: true},
+		{Name: "token", Value: MaskedSecretValue, IsSecret: true},
+	}
+	assert.Equal(t, []*api.InputField{
+		{Name: "username", Value: "root"},
+		{Name: "password", Value: "p1", IsSecret: true},
+		{Name: "token", Value: MaskedSecretValue, IsSecret: true},
+	}, keepMaskedSecrets(existing, desired))
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// defaultIdentifyingFields are the input fields identifying the targets of probes which do not report their
+// identifying fields, in order of preference
+var defaultIdentifyingFields = []string{"targetIdentifier", "nameOrAddress", "address"}
+
+// TargetMatcher returns true if an existing target is the desired target, which is then updated instead of added.
+// A matched target of another probe type is deleted and added again.
+type TargetMatcher func(existing, desired *api.Target) bool
+
+// MatchByIdentifyingFields matches the targets of the same category whose identifying fields have the same values.
+// The probe type of the existing target must be the one of the desired target, or only differ by an extra suffix,
+// so that the targets of other probes on the same address are not matched. The identifying fields are the ones
+// of the probe of the desired target, or of the existing target if unknown, and default to the first of
+// targetIdentifier, nameOrAddress and address set on the desired target.
+func MatchByIdentifyingFields(existing, desired *api.Target) bool {
+	if existing.Category != desired.Category || !strings.HasPrefix(existing.Type, desired.Type) {
+		return false
+	}
+	names := identifyingFields(existing, desired)
+	identified := false
+	for _, name := range names {
+		value := inputFieldValue(desired, name)
+		if value != inputFieldValue(existing, name) {
+			return false
+		}
+		identified = identified || value != ""
+	}
+	return identified
+}
+
+// matchTarget matches an existing target with the desired one with the given matcher, if any,
+// or by identifying fields
+func matchTarget(matcher TargetMatcher, existing, desired *api.Target) bool {
+	if matcher != nil {
+		return matcher(existing, desired)
+	}
+	return MatchByIdentifyingFields(existing, desired)
+}
+
+// identifyingFields returns the names of the fields identifying the given targets
+func identifyingFields(existing, desired *api.Target) []string {
+	if len(desired.IdentifyingFields) > 0 {
+		return desired.IdentifyingFields
+	}
+	if len(existing.IdentifyingFields) > 0 {
+		return existing.IdentifyingFields
+	}
+	for _, name := range defaultIdentifyingFields {
+		if inputFieldValue(desired, name) != "" {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// inputFieldValue returns the value of the input field of a target with the given name, or "" if not set
+func inputFieldValue(target *api.Target, name string) string {
+	for _, inputField := range target.InputFields {
+		if inputField.Name == name {
+			return inputField.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func newIdentifiedTarget(category, probeType string, identifyingFields []string, fields ...string) *api.Target {
+	target := &api.Target{Category: category, Type: probeType, IdentifyingFields: identifyingFields}
+	for i := 0; i+1 < len(fields); i += 2 {
+		target.InputFields = append(target.InputFields, &api.InputField{Name: fields[i], Value: fields[i+1]})
+	}
+	return target
+}
+
+func TestMatchByIdentifyingFields(t *testing.T) {
+	table := []struct {
+		existing *api.Target
+		desired  *api.Target
+
+		expectedMatch bool
+	}{
+		{
+			// Target identifier
+			existing: newIdentifiedTarget("Cloud Native", "Kubernetes-old", nil, "targetIdentifier", "cluster", "username", "a"),
+			desired:  newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster", "username", "b"),
+
+			expectedMatch: true,
+		},
+		{
+			// Other target identifier
+			existing: newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster"),
+			desired:  newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "other"),
+		},
+		{
+			// Other category
+			existing: newIdentifiedTarget("Hypervisor", "Kubernetes", nil, "targetIdentifier", "cluster"),
+			desired:  newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster"),
+		},
+		{
+			// Other probe type
+			existing: newIdentifiedTarget("Database Server", "SQLServer", nil, "nameOrAddress", "db.example.com"),
+			desired:  newIdentifiedTarget("Database Server", "MySQL", nil, "nameOrAddress", "db.example.com"),
+		},
+		{
+			// Name or address
+			existing: newIdentifiedTarget("Hypervisor", "vCenter", nil, "nameOrAddress", "vc.example.com", "username", "a"),
+			desired:  newIdentifiedTarget("Hypervisor", "vCenter", nil, "nameOrAddress", "vc.example.com", "username", "b"),
+
+			expectedMatch: true,
+		},
+		{
+			// Identifying fields of the existing target
+			existing: newIdentifiedTarget("Cloud Management", "AWS", []string{"address", "username"},
+				"address", "aws.amazon.com", "username", "key1"),
+			desired: newIdentifiedTarget("Cloud Management", "AWS", nil, "address", "aws.amazon.com", "username", "key2"),
+		},
+		{
+			// Identifying fields of the desired target
+			existing: newIdentifiedTarget("Cloud Management", "AWS", nil, "address", "aws.amazon.com", "username", "key1"),
+			desired: newIdentifiedTarget("Cloud Management", "AWS", []string{"address", "username"},
+				"address", "aws.amazon.com", "username", "key1", "password", "p"),
+
+			expectedMatch: true,
+		},
+		{
+			// No identifying field
+			existing: newIdentifiedTarget("Custom", "Custom", nil, "username", "a"),
+			desired:  newIdentifiedTarget("Custom", "Custom", nil, "username", "a"),
+		},
+		{
+			// Empty identifying fields
+			existing: newIdentifiedTarget("Custom", "Custom", []string{"name"}),
+			desired:  newIdentifiedTarget("Custom", "Custom", []string{"name"}),
+		},
+	}
+	for _, item := range table {
+		assert.Equal(t, item.expectedMatch, MatchByIdentifyingFields(item.existing, item.desired),
+			"%+v %+v", item.existing, item.desired)
+	}
+}
+
+func TestGetTargetId(t *testing.T) {
+	assert.Equal(t, "cluster", getTargetId(newIdentifiedTarget("", "", nil, "targetIdentifier", "cluster")))
+	assert.Equal(t, "vc", getTargetId(newIdentifiedTarget("", "", nil, "username", "a", "nameOrAddress", "vc")))
+	assert.Equal(t, "key", getTargetId(newIdentifiedTarget("", "", []string{"username"}, "address", "a", "username", "key")))
+	assert.Equal(t, "", getTargetId(newIdentifiedTarget("", "", nil, "username", "a")))
+}
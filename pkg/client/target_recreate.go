@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// ErrRecreateNotConfirmed is returned when adding a target requires to delete an existing target of another
+// probe type, and the deletion is not confirmed
+var ErrRecreateNotConfirmed = errors.New("recreating the target is not confirmed")
+
+// ErrSecretsNotRestorable is returned when adding a target requires to delete an existing target of another
+// probe type, whose secrets are masked by the server and could not be restored if adding the target failed
+var ErrSecretsNotRestorable = errors.New("the masked secrets of the target could not be restored")
+
+// RecreateConfirmation confirms the deletion of an existing target in order to add it again with the probe type
+// of the desired target. Adding the target fails with ErrRecreateNotConfirmed if it returns false.
+type RecreateConfirmation func(existing, desired *api.Target) bool
+
+// AllowRecreate returns a confirmation allowing or refusing to recreate all the targets
+func AllowRecreate(allow bool) RecreateConfirmation {
+	return func(existing, desired *api.Target) bool {
+		return allow
+	}
+}
+
+// confirmRecreate checks that an existing target can be deleted to add the desired one, which is allowed if there
+// is no confirmation
+func confirmRecreate(confirm RecreateConfirmation, existing, desired *api.Target) error {
+	if confirm == nil || confirm(existing, desired) {
+		return nil
+	}
+	return fmt.Errorf("target %v of type %v is not deleted to be added with type %v: %w",
+		getTargetId(desired), existing.Type, desired.Type, ErrRecreateNotConfirmed)
+}
+
+// checkRestorable checks that the snapshot of an existing target has no masked secret, so that the target can be
+// restored if adding the desired target fails after deleting the existing one
+func checkRestorable(snapshot, desired *api.Target) error {
+	var masked []string
+	for _, field := range snapshot.InputFields {
+		if field.IsSecret && field.Value == MaskedSecretValue {
+			masked = append(masked, field.Name)
+		}
+	}
+	if len(masked) == 0 {
+		return nil
+	}
+	return fmt.Errorf("target %v of type %v is not deleted to be added with type %v without its secret fields %v: %w",
+		getTargetId(desired), snapshot.Type, desired.Type, masked, ErrSecretsNotRestorable)
+}
+
+// recreateError reports the failure to add a target after deleting it with its former probe type,
+// and whether the former target was restored
+func recreateError(existing, desired *api.Target, addErr, restoreErr error) error {
+	if restoreErr != nil {
+		return fmt.Errorf("failed to add target %v of type %v after deleting it with type %v: %v; "+
+			"failed to restore the deleted target: %v", getTargetId(desired), desired.Type, existing.Type, addErr, restoreErr)
+	}
+	return fmt.Errorf("failed to add target %v of type %v after deleting it with type %v: %v; "+
+		"restored the deleted target", getTargetId(desired), desired.Type, existing.Type, addErr)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestConfirmRecreate(t *testing.T) {
+	existing := newIdentifiedTarget("Cloud Native", "Kubernetes-old", nil, "targetIdentifier", "cluster")
+	desired := newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster")
+	assert.NoError(t, confirmRecreate(nil, existing, desired))
+	assert.NoError(t, confirmRecreate(AllowRecreate(true), existing, desired))
+	err := confirmRecreate(AllowRecreate(false), existing, desired)
+	assert.True(t, errors.Is(err, ErrRecreateNotConfirmed))
+	assert.EqualError(t, err, "target cluster of type Kubernetes-old is not deleted to be added with type Kubernetes: "+
+		"recreating the target is not confirmed")
+	err = confirmRecreate(func(existing, desired *api.Target) bool {
+		return existing.Type == "Kubernetes-old"
+	}, existing, desired)
+	assert.NoError(t, err)
+}
+
+func TestRecreateError(t *testing.T) {
+	existing := newIdentifiedTarget("Cloud Native", "Kubernetes-old", nil, "targetIdentifier", "cluster")
+	desired := newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster")
+	assert.EqualError(t, recreateError(existing, desired, errors.New("down"), nil),
+		"failed to add target cluster of type Kubernetes after deleting it with type Kubernetes-old: down; "+
+			"restored the deleted target")
+	assert.EqualError(t, recreateError(existing, desired, errors.New("down"), errors.New("still down")),
+		"failed to add target cluster of type Kubernetes after deleting it with type Kubernetes-old: down; "+
+			"failed to restore the deleted target: still down")
+}
+
+func TestCheckRestorable(t *testing.T) {
+	desired := newIdentifiedTarget("Cloud Native", "Kubernetes", nil, "targetIdentifier", "cluster")
+	snapshot := newTestTarget("Kubernetes-old", "cluster", "p1")
+	assert.NoError(t, checkRestorable(snapshot, desired))
+	snapshot = newTestTarget("Kubernetes-old", "cluster", MaskedSecretValue)
+	err := checkRestorable(snapshot, desired)
+	assert.True(t, errors.Is(err, ErrSecretsNotRestorable))
+	assert.EqualError(t, err, "target cluster of type Kubernetes-old is not deleted to be added with type Kubernetes "+
+		"without its secret fields [password]: the masked secrets of the target could not be restored")
+}
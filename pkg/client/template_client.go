@@ -0,0 +1,116 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// TemplateClient manages the capacity templates used by plans and reservations via api service.
+type TemplateClient struct {
+	api *APIClient
+}
+
+// NewTemplateClient builds a client managing templates with the session of the given api service client
+func NewTemplateClient(apiClient *APIClient) *TemplateClient {
+	return &TemplateClient{api: apiClient}
+}
+
+// ListTemplates lists all the templates
+func (c *TemplateClient) ListTemplates() ([]api.Template, error) {
+	var templates []api.Template
+	if err := c.api.doJSON("list templates", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Templates)
+	}, nil, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListTemplatesByClass lists the templates describing entities of the given class, i.e. VirtualMachine
+func (c *TemplateClient) ListTemplatesByClass(class string) ([]api.Template, error) {
+	templates, err := c.ListTemplates()
+	if err != nil {
+		return nil, err
+	}
+	var matched []api.Template
+	for _, template := range templates {
+		if templateMatchesClass(&template, class) {
+			matched = append(matched, template)
+		}
+	}
+	return matched, nil
+}
+
+// GetTemplate gets the template with the given UUID
+func (c *TemplateClient) GetTemplate(uuid string) (*api.Template, error) {
+	var template api.Template
+	if err := c.api.doJSON("get template", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Templates).Name(uuid)
+	}, nil, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetTemplateByName gets the template with the given display name describing entities of the given class.
+// It returns nil if there is no such template.
+func (c *TemplateClient) GetTemplateByName(displayName, class string) (*api.Template, error) {
+	templates, err := c.ListTemplatesByClass(class)
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		if templates[i].DisplayName == displayName {
+			return &templates[i], nil
+		}
+	}
+	glog.V(4).Infof("template %v of class %v does not exist", displayName, class)
+	return nil, nil
+}
+
+// CreateTemplate creates a template and returns it with its UUID.
+// The class name of the template is the class of the described entity, i.e. VirtualMachine.
+func (c *TemplateClient) CreateTemplate(template *api.Template) (*api.Template, error) {
+	if template.DisplayName == "" || template.ClassName == "" {
+		return nil, fmt.Errorf("template display name and class name are required")
+	}
+	var created api.Template
+	if err := c.api.doJSON("create template", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Templates)
+	}, template, &created); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully created template %v with UUID %v.", created.DisplayName, created.UUID)
+	return &created, nil
+}
+
+// UpdateTemplate updates the template with the given UUID
+func (c *TemplateClient) UpdateTemplate(uuid string, template *api.Template) (*api.Template, error) {
+	var updated api.Template
+	if err := c.api.doJSON("update template", func() *Request {
+		return c.api.Put().Resource(api.Resource_Type_Templates).Name(uuid)
+	}, template, &updated); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully updated template %v.", uuid)
+	return &updated, nil
+}
+
+// DeleteTemplate deletes the template with the given UUID
+func (c *TemplateClient) DeleteTemplate(uuid string) error {
+	if err := c.api.doJSON("delete template", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Templates).Name(uuid)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully deleted template %v.", uuid)
+	return nil
+}
+
+// templateMatchesClass returns true if the template describes entities of the given class.
+// The server reports the class of templates as profile classes, i.e. VirtualMachineProfile.
+func templateMatchesClass(template *api.Template, class string) bool {
+	return template.ClassName == class || template.ClassName == class+"Profile"
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestTemplateClient(t *testing.T) {
+	templates := map[string]*api.Template{
+		"1": {UUID: "1", DisplayName: "small", ClassName: "VirtualMachineProfile"},
+		"2": {UUID: "2", DisplayName: "small", ClassName: "PhysicalMachineProfile"},
+		"3": {UUID: "3", DisplayName: "large", ClassName: "VirtualMachineProfile", Discovered: true},
+	}
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/templates":
+			var list []*api.Template
+			for _, uuid := range []string{"1", "2", "3", "4"} {
+				if template, found := templates[uuid]; found {
+					list = append(list, template)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == "POST" && r.URL.Path == "/templates":
+			var template api.Template
+			json.NewDecoder(r.Body).Decode(&template)
+			template.UUID = "4"
+			template.ClassName += "Profile"
+			templates["4"] = &template
+			json.NewEncoder(w).Encode(&template)
+		case r.Method == "PUT" && r.URL.Path == "/templates/4":
+			var template api.Template
+			json.NewDecoder(r.Body).Decode(&template)
+			templates["4"].ComputeResources = template.ComputeResources
+			json.NewEncoder(w).Encode(templates["4"])
+		case r.Method == "GET" && r.URL.Path == "/templates/4" && templates["4"] != nil:
+			json.NewEncoder(w).Encode(templates["4"])
+		case r.Method == "DELETE" && r.URL.Path == "/templates/4":
+			delete(templates, "4")
+			fmt.Fprint(w, "true")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"template not found"}`)
+		}
+	}, "templates")
+	defer server.Close()
+	templateClient := NewTemplateClient(apiClient)
+
+	vmTemplates, err := templateClient.ListTemplatesByClass(api.TemplateClassVirtualMachine)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(vmTemplates))
+
+	template, err := templateClient.GetTemplateByName("small", api.TemplateClassPhysicalMachine)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", template.UUID)
+	template, err = templateClient.GetTemplateByName("medium", api.TemplateClassVirtualMachine)
+	assert.NoError(t, err)
+	assert.Nil(t, template)
+
+	_, err = templateClient.CreateTemplate(&api.Template{DisplayName: "container"})
+	assert.Error(t, err)
+	created, err := templateClient.CreateTemplate(&api.Template{
+		DisplayName: "container",
+		ClassName:   api.TemplateClassContainer,
+		ComputeResources: []*api.TemplateResource{
+			{Stats: []*api.Stat{{Name: "numOfCpu", Value: 2}}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "4", created.UUID)
+	template, err = templateClient.GetTemplateByName("container", api.TemplateClassContainer)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", template.UUID)
+
+	template.ComputeResources[0].Stats[0].Value = 4
+	updated, err := templateClient.UpdateTemplate(template.UUID, template)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(4), updated.ComputeResources[0].Stats[0].Value)
+	template, err = templateClient.GetTemplate("4")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(4), template.ComputeResources[0].Stats[0].Value)
+
+	assert.NoError(t, templateClient.DeleteTemplate("4"))
+	_, err = templateClient.GetTemplate("4")
+	assert.EqualError(t, err, "unsuccessful get template response: 404 Not Found. template not found.")
+}
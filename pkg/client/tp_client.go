@@ -1,13 +1,13 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/avast/retry-go"
-	"github.com/davecgh/go-spew/spew"
 	"github.com/golang/glog"
 	"github.com/turbonomic/turbo-api/pkg/api"
 )
@@ -17,9 +17,14 @@ var (
 	retryDelay    = 1 * time.Second
 )
 
-// TPClient connects to topology processor service
+// TPClient connects to topology processor service.
+// It holds no mutable state and is safe for concurrent use by multiple goroutines.
 type TPClient struct {
 	*RESTClient
+	// Matcher of existing targets with the added ones, MatchByIdentifyingFields if nil
+	TargetMatcher TargetMatcher
+	// Confirmation of the recreation of existing targets of another probe type, allowed if nil
+	RecreateConfirmation RecreateConfirmation
 }
 
 func (c *TPClient) GetJwtToken(hydraToken string) (string, error) {
@@ -30,48 +35,81 @@ func (c *TPClient) GetHydraAccessToken() (string, error) {
 	panic("the program is trying to get hydra access token from TP Client, which should never happen")
 }
 
-// DiscoverTarget adds a target via Topology Processor service
-func (c *TPClient) DiscoverTarget(uuid string) (*Result, error) {
-	// Not implemented
-	return &Result{}, nil
+// DiscoverTarget triggers the rediscovery of a target via Topology Processor service
+// and returns its discovery status, which holds the time of the preceding discovery to pass to WaitForDiscovery
+func (c *TPClient) DiscoverTarget(uuid string) (*DiscoveryStatus, error) {
+	previous, err := c.GetDiscoveryStatus(uuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover target %s: %w", uuid, err)
+	}
+	request := c.Post().Resource(api.Resource_Type_Target).Name(uuid).SubResource("rediscover").
+		Header("Accept", "application/json")
+
+	glog.V(4).Infof("[DiscoverTarget] %v", request)
+
+	response, err := request.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover target %s: %s", uuid, err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("target discovery", response.statusCode, response.status, response.body)
+	}
+	status, err := c.GetDiscoveryStatus(uuid)
+	if err != nil {
+		return nil, err
+	}
+	status.PreviousValidated = previous.LastValidated
+	return status, nil
 }
 
-// AddTarget adds a target via topology processor service
-func (c *TPClient) AddTarget(target *api.Target) error {
+// GetDiscoveryStatus gets the validation and discovery status of a target via Topology Processor service
+func (c *TPClient) GetDiscoveryStatus(uuid string) (*DiscoveryStatus, error) {
+	targetID, err := strconv.ParseInt(uuid, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topology processor target ID %q: %v", uuid, err)
+	}
+	target, err := c.GetTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+	return newDiscoveryStatus(uuid, target.Status, target.LastValidationTime), nil
+}
+
+// WaitForDiscovery polls the status of a target via Topology Processor service until its validation
+// and discovery succeeds or fails, or the context is done or the timeout in opts expires. Set opts.Since to
+// the status returned by DiscoverTarget to wait for the discovery it triggered.
+func (c *TPClient) WaitForDiscovery(ctx context.Context, uuid string, opts *DiscoveryWaitOptions) (*DiscoveryStatus, error) {
+	if _, err := strconv.ParseInt(uuid, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid topology processor target ID %q: %v", uuid, err)
+	}
+	return waitForDiscovery(ctx, uuid, opts, c.GetDiscoveryStatus)
+}
+
+// AddTarget adds a target via topology processor service, or updates it if it already exists with other input fields.
+// An existing target of another probe type is deleted and added again, and restored if adding it fails.
+func (c *TPClient) AddTarget(target *api.Target) (TargetOutcome, error) {
 	glog.V(2).Infof("Getting probe ID for probe with category %v and type %v.",
 		target.Category, target.Type)
 
-	probeID, err := c.getProbeID(target.Type, target.Category)
+	probe, probes, err := c.getProbes(target.Type, target.Category)
 	if err != nil {
-		return fmt.Errorf("failed to get probe ID: %v", err)
+		return "", fmt.Errorf("failed to get probe ID: %w", err)
 	}
+	target = identifyTarget(target, probe)
 
 	// Check if the given target already exists
-	targetName := getTargetId(target)
-	existingTarget, err := c.findTarget(targetName)
+	existingTarget, err := c.findTarget(target, probes)
 	if err != nil {
-		return err
-	}
-
-	if existingTarget != nil {
-		glog.V(2).Infof("Target %v already exists with ID %v.",
-			targetName, existingTarget)
-		if probeID == existingTarget.TargetSpec.ProbeID {
-			return c.updateTarget(existingTarget, target)
-		}
-		glog.V(2).Infof("Delete and re-add the target to update the probe id "+
-			"(old probe id: %v, new probe id: %v, old target: %v, new target: %v of type %v)",
-			existingTarget.TargetSpec.ProbeID, probeID, existingTarget, target.DisplayName, target.Type)
-		if err := c.deleteTarget(existingTarget); err != nil {
-			return fmt.Errorf("failed to delete target %v of probe id %v which is necessary "+
-				"due to probe type changed to %v (new id %v); error: %v",
-				existingTarget.DisplayName, existingTarget.TargetSpec.ProbeID, target.Type, probeID, err)
-		}
+		return "", err
 	}
+	return c.upsertTarget(target, probe, probes, existingTarget)
+}
 
-	// Add the target which belongs to the probe with the given probeID
-	glog.V(2).Infof("Starting to add target %v for probe %v with ID : %v",
-		targetName, target.Type, probeID)
+// upsertTarget creates a target of the given probe, or updates the existing target matching it if any
+func (c *TPClient) upsertTarget(target *api.Target, probe *api.ProbeDescription,
+	probes map[int64]*api.ProbeDescription, existingTarget *api.TargetInfo) (TargetOutcome, error) {
+	probeID := probe.ID
+	targetName := getTargetId(target)
 
 	// Construct the TargetSpec required by the rest api
 	inputFields, communicationBindingChannel := c.extractCommunicationBindingChannel(target.InputFields)
@@ -81,7 +119,50 @@ func (c *TPClient) AddTarget(target *api.Target) error {
 		InputFields:                 inputFields,
 		CommunicationBindingChannel: communicationBindingChannel,
 	}
+	if existingTarget == nil {
+		// Add the target which belongs to the probe with the given probeID
+		glog.V(2).Infof("Starting to add target %v for probe %v with ID : %v",
+			targetName, target.Type, probeID)
+		if err := c.createTarget(targetSpec); err != nil {
+			return "", err
+		}
+		return TargetCreated, nil
+	}
 
+	glog.V(2).Infof("Target %v already exists with ID %v.",
+		targetName, existingTarget)
+	if probeID == existingTarget.TargetSpec.ProbeID {
+		return c.updateTarget(existingTarget, target)
+	}
+	existing := tpTarget(existingTarget, probes)
+	if err := confirmRecreate(c.RecreateConfirmation, existing, target); err != nil {
+		return "", err
+	}
+	glog.V(2).Infof("Delete and re-add the target to update the probe id "+
+		"(old probe id: %v, new probe id: %v, old target: %v, new target: %v of type %v)",
+		existingTarget.TargetSpec.ProbeID, probeID, existingTarget, target.DisplayName, target.Type)
+	// Snapshot the existing target to restore it if adding the target fails
+	snapshot := &api.TargetSpec{
+		ProbeID:                     existingTarget.TargetSpec.ProbeID,
+		DerivedTargetIDs:            []string{},
+		InputFields:                 existingTarget.TargetSpec.InputFields,
+		CommunicationBindingChannel: existingTarget.TargetSpec.CommunicationBindingChannel,
+	}
+	if err := c.deleteTarget(existingTarget); err != nil {
+		return "", fmt.Errorf("failed to delete target %v of probe id %v which is necessary "+
+			"due to probe type changed to %v (new id %v); error: %v",
+			existingTarget.DisplayName, existingTarget.TargetSpec.ProbeID, target.Type, probeID, err)
+	}
+	if err := c.createTarget(targetSpec); err != nil {
+		glog.Warningf("Failed to add target %v for probe %v, restoring it for probe id %v: %v",
+			targetName, target.Type, snapshot.ProbeID, err)
+		return "", recreateError(existing, target, err, c.createTarget(snapshot))
+	}
+	return TargetRecreated, nil
+}
+
+// createTarget creates a target via topology processor service
+func (c *TPClient) createTarget(targetSpec *api.TargetSpec) error {
 	// Create the rest api request
 	targetData, err := json.Marshal(targetSpec)
 	if err != nil {
@@ -98,12 +179,12 @@ func (c *TPClient) AddTarget(target *api.Target) error {
 	// Execute the request
 	response, err := request.Do()
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return fmt.Errorf("request %v failed: %w", request, err)
 	}
 	glog.V(4).Infof("Response %+v", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target addition", response.status, response.body)
+		return buildStatusError("target addition", response.statusCode, response.status, response.body)
 	}
 
 	// Unmarshal the response and parse out the target ID
@@ -111,7 +192,6 @@ func (c *TPClient) AddTarget(target *api.Target) error {
 	_ = json.Unmarshal([]byte(response.body), &targetInfo)
 	glog.V(2).Infof("Successfully added target via Topology Processor service. Target ID: %v",
 		targetInfo.TargetID)
-
 	return nil
 }
 
@@ -131,86 +211,295 @@ func (c *TPClient) extractCommunicationBindingChannel(inputFields []*api.InputFi
 	return extractedInputFields, communicationBindingChannel
 }
 
-func (c *TPClient) findTarget(targetName string) (*api.TargetInfo, error) {
+// findTarget finds the existing target matching the given one, if any.
+// The existing targets are matched with the category, type and identifying fields of their probe.
+func (c *TPClient) findTarget(target *api.Target, probes map[int64]*api.ProbeDescription) (*api.TargetInfo, error) {
+	var existingTarget *api.TargetInfo
+	err := c.forEachTarget(func(targetInfo *api.TargetInfo) bool {
+		if targetInfo.TargetSpec == nil {
+			return true
+		}
+		existing := tpTarget(targetInfo, probes)
+		if glog.V(4) {
+			// The input fields are not logged, since topology processor does not mask the secret ones
+			glog.Infof("Trying to match with target %v of category %v and type %v",
+				existing.UUID, existing.Category, existing.Type)
+		}
+		if matchTarget(c.TargetMatcher, existing, target) {
+			existingTarget = targetInfo
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existingTarget == nil {
+		glog.V(4).Infof("target %v does not exist", getTargetId(target))
+	}
+	return existingTarget, nil
+}
+
+// identifyTarget returns the target identified by the identifying fields of its probe, unless it has its own
+func identifyTarget(target *api.Target, probe *api.ProbeDescription) *api.Target {
+	if len(target.IdentifyingFields) > 0 {
+		return target
+	}
+	identified := *target
+	identified.IdentifyingFields = probe.IdentifyingFields
+	return &identified
+}
+
+// tpTarget describes a target of topology processor with the category, type and identifying fields of its probe
+func tpTarget(targetInfo *api.TargetInfo, probes map[int64]*api.ProbeDescription) *api.Target {
+	target := &api.Target{
+		UUID:        strconv.FormatInt(targetInfo.TargetID, 10),
+		DisplayName: targetInfo.DisplayName,
+		InputFields: targetInfo.TargetSpec.InputFields,
+	}
+	if probe, found := probes[targetInfo.TargetSpec.ProbeID]; found {
+		target.Category = probe.Category
+		target.Type = probe.Type
+		target.IdentifyingFields = probe.IdentifyingFields
+	}
+	return target
+}
+
+// GetTargets returns all the targets registered in the topology processor,
+// including their validation and discovery status.
+func (c *TPClient) GetTargets() ([]api.TargetInfo, error) {
+	var targets []api.TargetInfo
+	err := c.forEachTarget(func(target *api.TargetInfo) bool {
+		targets = append(targets, *target)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// GetTarget returns the target with the given ID, including its validation and discovery status.
+func (c *TPClient) GetTarget(targetID int64) (*api.TargetInfo, error) {
+	request := c.Get().Resource(api.Resource_Type_Target).Name(strconv.FormatInt(targetID, 10)).
+		Header("Content-Type", "application/json").
+		Header("Accept", "application/json")
+
+	response, err := request.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get target request %v: %w", request, err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("get target", response.statusCode, response.status, response.body)
+	}
+	var target api.TargetInfo
+	if err := json.Unmarshal([]byte(response.body), &target); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal get target response: %v", err)
+	}
+	return &target, nil
+}
+
+// forEachTarget streams the list of targets from the topology processor and calls visit on each of them,
+// until visit returns false or there are no more targets.
+func (c *TPClient) forEachTarget(visit func(target *api.TargetInfo) bool) error {
 	// Get a list of targets from the Turbo server
 	request := c.Get().Resource(api.Resource_Type_Target).
 		Header("Content-Type", "application/json").
 		Header("Accept", "application/json")
 
-	response, err := request.Do()
+	// Stream the response since the list of targets can be large
+	response, err := request.Stream()
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute find target request %v: %v",
+		return fmt.Errorf("failed to execute find target request %v: %w",
 			request, err)
 	}
 
-	glog.V(4).Infof("Received response from find target request %v: %+v",
-		request, response)
+	glog.V(4).Infof("Received response from find target request %v: %v",
+		request, response.Status)
+
+	if response.StatusCode != 200 {
+		return response.Error("find target")
+	}
+	defer response.Close()
 
+	decoder := NewJSONArrayFieldDecoder(response.Body, "targets")
+	for decoder.More() {
+		var target api.TargetInfo
+		if err := decoder.Decode(&target); err != nil {
+			return fmt.Errorf("failed to unmarshal get target response: %v", err)
+		}
+		if !visit(&target) {
+			return nil
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return fmt.Errorf("failed to unmarshal get target response: %v", err)
+	}
+	return nil
+}
+
+// Ping checks that topology processor service is reachable and responding
+func (c *TPClient) Ping(ctx context.Context) error {
+	response, err := c.Get().Resource(api.Resource_Type_Probe).Context(ctx).NoCache().
+		Header("Accept", "application/json").
+		Do()
+	if err != nil {
+		return fmt.Errorf("topology processor is not reachable: %v", err)
+	}
 	if response.statusCode != 200 {
-		return nil, buildResponseError("find target", response.status, response.body)
+		return buildStatusError("topology processor ping", response.statusCode, response.status, response.body)
 	}
+	return nil
+}
 
-	var targetsMap map[string][]api.TargetInfo
-	if err := json.Unmarshal([]byte(response.body), &targetsMap); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal get target response: %v", err)
+// GetProbes returns the probe types registered in the topology processor.
+func (c *TPClient) GetProbes() ([]api.ProbeDescription, error) {
+	return c.listProbes(true)
+}
+
+// listProbes lists the probe types registered in the topology processor, from the response cache if cached is true,
+// or from the server otherwise, i.e. to find probes registered since the probes were cached.
+func (c *TPClient) listProbes(cached bool) ([]api.ProbeDescription, error) {
+	request := c.Get().Resource(api.Resource_Type_Probe).
+		Header("Content-Type", "application/json").
+		Header("Accept", "application/json")
+	if !cached {
+		request.NoCache()
 	}
-	glog.V(4).Infof("Successfully parsed response body into targetsMap: %v",
-		spew.Sdump(targetsMap))
-	targets, found := targetsMap["targets"]
+
+	response, err := request.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get probe request %+v: %w",
+			request, err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("get probe", response.statusCode, response.status, response.body)
+	}
+	glog.V(4).Infof("Received response from get probe request %+v: %+v", request, response)
+	// Parse the response - list of probes
+	var probesMap map[string][]api.ProbeDescription
+	if err = json.Unmarshal([]byte(response.body), &probesMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal get probe response: %v", err)
+	}
+	probes, found := probesMap["probes"]
 	if !found {
-		return nil, fmt.Errorf("failed to find key \"targets\" from response")
+		return nil, fmt.Errorf("failed to find key \"probes\" from response")
+	}
+	return probes, nil
+}
+
+// GetProbeRegistrations returns the probe instances currently registered with the topology processor,
+// together with the state of their connection.
+func (c *TPClient) GetProbeRegistrations() ([]api.ProbeRegistration, error) {
+	request := c.Get().Resource(api.Resource_Type_Probe).Name("registration").
+		Header("Content-Type", "application/json").
+		Header("Accept", "application/json")
+
+	response, err := request.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get probe registration request %+v: %w",
+			request, err)
+	}
+	if response.statusCode != 200 {
+		return nil, buildStatusError("get probe registration", response.statusCode, response.status, response.body)
+	}
+	var registrations []api.ProbeRegistration
+	if err = json.Unmarshal([]byte(response.body), &registrations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal get probe registration response: %v", err)
 	}
+	return registrations, nil
+}
+
+// ProbeStatus describes a probe type and the probe instances registered for it.
+type ProbeStatus struct {
+	Probe         *api.ProbeDescription
+	Registrations []api.ProbeRegistration
+}
 
-	for _, target := range targets {
-		if target.TargetSpec == nil {
-			continue
+// Connected returns true if at least one instance of the probe is registered with the topology processor.
+func (s *ProbeStatus) Connected() bool {
+	return s.Probe != nil && len(s.Registrations) > 0
+}
+
+// GetProbeStatus returns the status of the probe with the given type and category.
+// The Probe of the returned status is nil if the probe type has never been registered.
+// The probes are not read from the response cache, which may not hold the probes registered recently.
+func (c *TPClient) GetProbeStatus(probeType, probeCategory string) (*ProbeStatus, error) {
+	probes, err := c.listProbes(false)
+	if err != nil {
+		return nil, err
+	}
+	status := &ProbeStatus{}
+	for i := range probes {
+		if probes[i].Category == probeCategory && probes[i].Type == probeType {
+			status.Probe = &probes[i]
+			break
 		}
-		for _, inputField := range target.TargetSpec.InputFields {
-			if inputField.Name == "targetIdentifier" &&
-				inputField.Value == targetName {
-				return &target, nil
-			}
+	}
+	if status.Probe == nil {
+		return status, nil
+	}
+	registrations, err := c.GetProbeRegistrations()
+	if err != nil {
+		return nil, err
+	}
+	for _, registration := range registrations {
+		if registration.ProbeID == status.Probe.ID {
+			status.Registrations = append(status.Registrations, registration)
 		}
 	}
-	glog.V(4).Infof("target %v does not exist", targetName)
-	return nil, nil
+	return status, nil
+}
+
+// IsProbeConnected returns true if an instance of the probe with the given type and category
+// is connected to the topology processor, so that targets of the probe can be validated and discovered.
+func (c *TPClient) IsProbeConnected(probeType, probeCategory string) (bool, error) {
+	status, err := c.GetProbeStatus(probeType, probeCategory)
+	if err != nil {
+		return false, err
+	}
+	return status.Connected(), nil
 }
 
 func (c *TPClient) getProbeID(probeType, probeCategory string) (int64, error) {
-	// Execute get probe request
-	request := c.Get().Resource(api.Resource_Type_Probe).
-		Header("Content-Type", "application/json").
-		Header("Accept", "application/json")
-	// Get the Probe ID based on probe type and probe category
-	// Retry 5 times with 1 second delay
-	var probeID int64
+	probe, _, err := c.getProbes(probeType, probeCategory)
+	if err != nil {
+		return 0, err
+	}
+	return probe.ID, nil
+}
+
+// getProbes gets the probe with the given type and category together with all the probes by ID.
+// It retries 5 times with 1 second delay until the probe is registered, listing the probes from the server
+// rather than from the response cache once the probe is not found in the cached probes.
+func (c *TPClient) getProbes(probeType, probeCategory string) (*api.ProbeDescription,
+	map[int64]*api.ProbeDescription, error) {
+	var probe *api.ProbeDescription
+	probesByID := make(map[int64]*api.ProbeDescription)
+	cached := true
 	errs := retry.Do(
 		func() error {
-			response, err := request.Do()
+			probes, err := c.listProbes(cached)
 			if err != nil {
-				return fmt.Errorf("failed to execute get probe request %+v: %v",
-					request, err)
-			}
-			if response.statusCode != 200 {
-				return buildResponseError("get probe", response.status, response.body)
-			}
-			glog.V(4).Infof("Received response from get probe request %+v: %+v", request, response)
-			// Parse the response - list of probes
-			var probesMap map[string][]api.ProbeDescription
-			if err = json.Unmarshal([]byte(response.body), &probesMap); err != nil {
-				return fmt.Errorf("failed to unmarshal get probe response: %v", err)
+				return err
 			}
-			probes, found := probesMap["probes"]
-			if !found {
-				return fmt.Errorf("failed to find key \"probes\" from response")
+			if cached && !hasProbe(probes, probeType, probeCategory) {
+				// The probe may have been registered since the probes were cached
+				cached = false
+				if probes, err = c.listProbes(false); err != nil {
+					return err
+				}
 			}
-			for _, probe := range probes {
-				if probe.Category == probeCategory &&
-					probe.Type == probeType {
-					probeID = probe.ID
-					return nil
+			for i := range probes {
+				probesByID[probes[i].ID] = &probes[i]
+				if probes[i].Category == probeCategory &&
+					probes[i].Type == probeType {
+					probe = &probes[i]
 				}
 			}
+			if probe != nil {
+				return nil
+			}
 			return fmt.Errorf("failed to find probe with category %v and type %v",
 				probeCategory, probeType)
 		},
@@ -223,19 +512,41 @@ func (c *TPClient) getProbeID(probeType, probeCategory string) (int64, error) {
 		retry.LastErrorOnly(true),
 	)
 	if errs != nil {
-		return 0, errs
+		return nil, nil, errs
 	}
-	return probeID, nil
+	return probe, probesByID, nil
 }
 
-func (c *TPClient) updateTarget(existingTarget *api.TargetInfo, input *api.Target) error {
+// hasProbe returns true if the probe with the given type and category is in the given list
+func hasProbe(probes []api.ProbeDescription, probeType, probeCategory string) bool {
+	for i := range probes {
+		if probes[i].Category == probeCategory && probes[i].Type == probeType {
+			return true
+		}
+	}
+	return false
+}
+
+// updateTarget updates the input fields of an existing target, unless they are already the desired ones
+func (c *TPClient) updateTarget(existingTarget *api.TargetInfo, input *api.Target) (TargetOutcome, error) {
 	// existingTarget.TargetSpec is guaranteed to be non nil
 	inputFields, communicationBindingChannel := c.extractCommunicationBindingChannel(input.InputFields)
-	existingTarget.TargetSpec.InputFields = inputFields
+	diff := DiffInputFields(existingTarget.TargetSpec.InputFields, inputFields)
+	if communicationBindingChannel != existingTarget.TargetSpec.CommunicationBindingChannel {
+		diff.Changed = append(diff.Changed, api.CommunicationBindingChannel)
+	}
+	if diff.Empty() {
+		glog.V(2).Infof("Target %v is unchanged.", existingTarget.TargetID)
+		return TargetUnchanged, nil
+	}
+	glog.V(4).Infof("Updating target %v with changed fields %v and masked secrets %v.",
+		existingTarget.TargetID, diff.Changed, diff.MaskedSecrets)
+	// Topology processor does not mask secrets, so it would store the masked value
+	existingTarget.TargetSpec.InputFields = keepMaskedSecrets(existingTarget.TargetSpec.InputFields, inputFields)
 	existingTarget.TargetSpec.CommunicationBindingChannel = communicationBindingChannel
 	targetData, err := json.Marshal(existingTarget.TargetSpec)
 	if err != nil {
-		return fmt.Errorf("failed to marshall input fields array: %v", err)
+		return "", fmt.Errorf("failed to marshall input fields array: %v", err)
 	}
 	// Create the rest api request
 	request := c.Put().Resource(api.Resource_Type_Target).Name(strconv.FormatInt(existingTarget.TargetID, 10)).
@@ -249,15 +560,15 @@ func (c *TPClient) updateTarget(existingTarget *api.TargetInfo, input *api.Targe
 	// Execute the request
 	response, err := request.Do()
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return "", fmt.Errorf("request %v failed: %w", request, err)
 	}
 	glog.V(4).Infof("Response %+v", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target update", response.status, response.body)
+		return "", buildStatusError("target update", response.statusCode, response.status, response.body)
 	}
 	glog.V(2).Infof("Successfully updated target via Topology Processor service: %v.", existingTarget.TargetID)
-	return nil
+	return TargetUpdated, nil
 }
 
 // deleteTarget deletes an existing target
@@ -272,12 +583,12 @@ func (c *TPClient) deleteTarget(existingTarget *api.TargetInfo) error {
 	// Execute the request
 	response, err := request.Do()
 	if err != nil {
-		return fmt.Errorf("request %v failed: %s", request, err)
+		return fmt.Errorf("request %v failed: %w", request, err)
 	}
 	glog.V(4).Infof("Response %+v", response)
 
 	if response.statusCode != 200 {
-		return buildResponseError("target delete", response.status, response.body)
+		return buildStatusError("target delete", response.statusCode, response.status, response.body)
 	}
 	glog.V(2).Infof("Successfully deleted target %v of probe id %v via Topology Processor service.",
 		existingTarget.DisplayName, existingTarget.TargetSpec.ProbeID)
@@ -1,11 +1,13 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/turbonomic/turbo-api/pkg/api"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -17,9 +19,9 @@ func TestGetProbeIDWithRetry(t *testing.T) {
 	probeCategory := "Cloud Native"
 	baseURL, _ := url.Parse("http://localhost")
 	tpClient := &TPClient{
-		&RESTClient{&http.Client{Transport: &http.Transport{
+		RESTClient: &RESTClient{client: &http.Client{Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}}, baseURL, TopologyProcessorPath, nil}}
+		}}, baseURL: baseURL, apiPath: TopologyProcessorPath}}
 	start := time.Now()
 	_, err := tpClient.getProbeID(probeType, probeCategory)
 	assert.Error(t, err)
@@ -51,3 +53,113 @@ func TestExtractCommunicationBindingChannel(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(expectedInputFields, extractedInputFields), "Expected input fields: %v,"+
 		" are not the same as the actual: %v", expectedInputFields, extractedInputFields)
 }
+
+func newTestTPClient(handler http.HandlerFunc) (*TPClient, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	serverURL, _ := url.Parse(server.URL)
+	return &TPClient{RESTClient: NewRESTClient(server.Client(), serverURL, TopologyProcessorPath)}, server
+}
+
+func TestTPClient_GetProbeStatus(t *testing.T) {
+	tpClient, server := newTestTPClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/probe":
+			fmt.Fprint(w, `{"probes":[{"id":"1","category":"Cloud Native","type":"Kubernetes"},`+
+				`{"id":"2","category":"Hypervisor","type":"vCenter"}]}`)
+		case "/probe/registration":
+			fmt.Fprint(w, `[{"id":"11","probeId":"1","communicationBindingChannel":"a","healthState":"NORMAL"},`+
+				`{"id":"12","probeId":"1","communicationBindingChannel":"b","healthState":"NORMAL"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	table := []struct {
+		probeType             string
+		probeCategory         string
+		expectedRegistrations []string
+		expectsConnected      bool
+	}{
+		{
+			probeType:             "Kubernetes",
+			probeCategory:         "Cloud Native",
+			expectedRegistrations: []string{"a", "b"},
+			expectsConnected:      true,
+		},
+		{
+			probeType:     "vCenter",
+			probeCategory: "Hypervisor",
+		},
+		{
+			probeType:     "Kubernetes",
+			probeCategory: "Custom",
+		},
+	}
+	for _, item := range table {
+		status, err := tpClient.GetProbeStatus(item.probeType, item.probeCategory)
+		assert.NoError(t, err)
+		var channels []string
+		for _, registration := range status.Registrations {
+			channels = append(channels, registration.CommunicationBindingChannel)
+		}
+		assert.Equal(t, item.expectedRegistrations, channels)
+		connected, err := tpClient.IsProbeConnected(item.probeType, item.probeCategory)
+		assert.NoError(t, err)
+		assert.Equal(t, item.expectsConnected, connected)
+	}
+}
+
+func TestTPClient_GetTargets(t *testing.T) {
+	tpClient, server := newTestTPClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/target":
+			fmt.Fprint(w, `{"targets":[{"targetId":"1","displayName":"foo","status":"Validated",`+
+				`"lastValidationTime":"2020-01-01T00:00:00Z","spec":{"probeId":"2","inputFields":`+
+				`[{"name":"targetIdentifier","value":"foo"}]}},{"targetId":"3","displayName":"bar"}]}`)
+		case "/target/1":
+			fmt.Fprint(w, `{"targetId":"1","displayName":"foo","status":"Validated"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"target not found"}`)
+		}
+	})
+	defer server.Close()
+
+	targets, err := tpClient.GetTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(targets))
+	assert.Equal(t, "Validated", targets[0].Status)
+	assert.Equal(t, "2020-01-01T00:00:00Z", targets[0].LastValidationTime)
+
+	existing, err := tpClient.findTarget(&api.Target{InputFields: []*api.InputField{{Name: "targetIdentifier", Value: "foo"}}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), existing.TargetID)
+	existing, err = tpClient.findTarget(&api.Target{InputFields: []*api.InputField{{Name: "targetIdentifier", Value: "bar"}}}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	target, err := tpClient.GetTarget(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", target.DisplayName)
+	_, err = tpClient.GetTarget(2)
+	assert.EqualError(t, err, "unsuccessful get target response: 404 Not Found. target not found.")
+}
+
+func TestTPClient_Ping(t *testing.T) {
+	tpClient, server := newTestTPClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/probe" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"probes":[]}`)
+	})
+	assert.NoError(t, tpClient.Ping(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, tpClient.Ping(ctx))
+	server.Close()
+	err := tpClient.Ping(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "topology processor is not reachable")
+}
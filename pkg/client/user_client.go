@@ -0,0 +1,196 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// UserClient manages the users, their roles and scopes and the AD group mappings via api service.
+type UserClient struct {
+	api *APIClient
+}
+
+// NewUserClient builds a client managing users with the session of the given api service client
+func NewUserClient(apiClient *APIClient) *UserClient {
+	return &UserClient{api: apiClient}
+}
+
+// ListUsers lists the local and LDAP users
+func (c *UserClient) ListUsers() ([]api.User, error) {
+	var users []api.User
+	if err := c.api.doJSON("list users", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Users)
+	}, nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUser gets the user with the given UUID
+func (c *UserClient) GetUser(uuid string) (*api.User, error) {
+	var user api.User
+	if err := c.api.doJSON("get user", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Users).Name(uuid)
+	}, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByName gets the user with the given username. It returns nil if there is no such user.
+func (c *UserClient) GetUserByName(username string) (*api.User, error) {
+	users, err := c.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].Username == username {
+			return &users[i], nil
+		}
+	}
+	glog.V(4).Infof("user %v does not exist", username)
+	return nil, nil
+}
+
+// CreateUser creates a user and returns it with its UUID.
+// Local users need a password, and shared users need a scope.
+func (c *UserClient) CreateUser(user *api.User) (*api.User, error) {
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+	var created api.User
+	if err := c.api.doJSON("create user", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Users)
+	}, user, &created); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully created user %v with UUID %v.", created.Username, created.UUID)
+	return &created, nil
+}
+
+// UpdateUser updates the user with the given UUID
+func (c *UserClient) UpdateUser(uuid string, user *api.User) (*api.User, error) {
+	var updated api.User
+	if err := c.api.doJSON("update user", func() *Request {
+		return c.api.Put().Resource(api.Resource_Type_Users).Name(uuid)
+	}, user, &updated); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully updated user %v.", uuid)
+	return &updated, nil
+}
+
+// DeleteUser deletes the user with the given UUID
+func (c *UserClient) DeleteUser(uuid string) error {
+	if err := c.api.doJSON("delete user", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Users).Name(uuid)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully deleted user %v.", uuid)
+	return nil
+}
+
+// AssignRole assigns the given role to the user with the given UUID, scoped to the groups with the given UUIDs,
+// if any. The user is shared if the role is a shared one, which requires a scope, and dedicated otherwise.
+func (c *UserClient) AssignRole(uuid, roleName string, scopeUUIDs ...string) (*api.User, error) {
+	userType, scope, err := scopeOf(roleName, scopeUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	user, err := c.GetUser(uuid)
+	if err != nil {
+		return nil, err
+	}
+	user.RoleName = roleName
+	user.Roles = []*api.Role{{Name: roleName}}
+	user.Type, user.Scope = userType, scope
+	return c.UpdateUser(uuid, user)
+}
+
+// ListADGroups lists the mappings of AD groups to roles
+func (c *UserClient) ListADGroups() ([]api.ADGroup, error) {
+	var groups []api.ADGroup
+	if err := c.api.doJSON("list AD groups", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Users).SubResource("ad", "groups")
+	}, nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// CreateADGroup maps an AD group to a role, scoped to the groups with the given UUIDs if any
+func (c *UserClient) CreateADGroup(displayName, roleName string, scopeUUIDs ...string) (*api.ADGroup, error) {
+	if displayName == "" || roleName == "" {
+		return nil, fmt.Errorf("AD group name and role name are required")
+	}
+	userType, scope, err := scopeOf(roleName, scopeUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	group := &api.ADGroup{DisplayName: displayName, Type: userType, RoleName: roleName, Scope: scope}
+	var created api.ADGroup
+	if err := c.api.doJSON("create AD group", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Users).SubResource("ad", "groups")
+	}, group, &created); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully mapped AD group %v to role %v.", displayName, roleName)
+	return &created, nil
+}
+
+// UpdateADGroup updates the mapping of an AD group
+func (c *UserClient) UpdateADGroup(group *api.ADGroup) (*api.ADGroup, error) {
+	var updated api.ADGroup
+	if err := c.api.doJSON("update AD group", func() *Request {
+		return c.api.Put().Resource(api.Resource_Type_Users).SubResource("ad", "groups")
+	}, group, &updated); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully updated the mapping of AD group %v.", group.DisplayName)
+	return &updated, nil
+}
+
+// DeleteADGroup deletes the mapping of the AD group with the given UUID
+func (c *UserClient) DeleteADGroup(uuid string) error {
+	if err := c.api.doJSON("delete AD group", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Users).SubResource("ad", "groups", uuid)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully deleted the mapping of AD group %v.", uuid)
+	return nil
+}
+
+// validateUser checks the fields required to create a user
+func validateUser(user *api.User) error {
+	if user.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if (user.LoginProvider == "" || user.LoginProvider == api.LoginProviderLocal) && user.Password == "" {
+		return fmt.Errorf("password of local user %s is required", user.Username)
+	}
+	if user.Type == api.UserTypeShared && len(user.Scope) == 0 {
+		return fmt.Errorf("scope of shared user %s is required", user.Username)
+	}
+	return nil
+}
+
+// scopeOf returns the type of the users with the given role and the scope of the groups with the given UUIDs.
+// The users with a shared role are shared, and need a scope.
+func scopeOf(roleName string, scopeUUIDs []string) (string, []*api.BaseDTO, error) {
+	userType := api.UserTypeDedicated
+	if roleName == api.RoleSharedAdvisor || roleName == api.RoleSharedObserver {
+		userType = api.UserTypeShared
+		if len(scopeUUIDs) == 0 {
+			return "", nil, fmt.Errorf("scope of shared role %s is required", roleName)
+		}
+	}
+	var scope []*api.BaseDTO
+	for _, uuid := range scopeUUIDs {
+		scope = append(scope, &api.BaseDTO{UUID: uuid})
+	}
+	return userType, scope, nil
+}
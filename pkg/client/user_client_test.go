@@ -0,0 +1,145 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestUserClient_Users(t *testing.T) {
+	var lock sync.Mutex
+	users := map[string]*api.User{
+		"1": {UUID: "1", Username: "administrator", LoginProvider: "Local", RoleName: api.RoleAdministrator},
+	}
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/users":
+			var list []*api.User
+			for _, uuid := range []string{"1", "2"} {
+				if user, found := users[uuid]; found {
+					list = append(list, user)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == "POST" && r.URL.Path == "/users":
+			var user api.User
+			json.NewDecoder(r.Body).Decode(&user)
+			assert.Equal(t, "secret", user.Password)
+			user.UUID, user.Password = "2", ""
+			users["2"] = &user
+			json.NewEncoder(w).Encode(&user)
+		case r.Method == "GET" && r.URL.Path == "/users/2" && users["2"] != nil:
+			json.NewEncoder(w).Encode(users["2"])
+		case r.Method == "PUT" && r.URL.Path == "/users/2":
+			var user api.User
+			json.NewDecoder(r.Body).Decode(&user)
+			users["2"] = &user
+			json.NewEncoder(w).Encode(&user)
+		case r.Method == "DELETE" && r.URL.Path == "/users/2":
+			delete(users, "2")
+			fmt.Fprint(w, "true")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "users")
+	defer server.Close()
+	userClient := NewUserClient(apiClient)
+
+	_, err := userClient.CreateUser(&api.User{Username: "bot"})
+	assert.EqualError(t, err, "password of local user bot is required")
+	_, err = userClient.CreateUser(&api.User{Username: "bot", Password: "secret", Type: api.UserTypeShared})
+	assert.EqualError(t, err, "scope of shared user bot is required")
+
+	created, err := userClient.CreateUser(&api.User{Username: "bot", Password: "secret", RoleName: api.RoleObserver})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", created.UUID)
+
+	user, err := userClient.GetUserByName("bot")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", user.UUID)
+	user, err = userClient.GetUserByName("nobody")
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	// The type of the user depends on the role, not on the scope
+	user, err = userClient.AssignRole("2", api.RoleSharedObserver, "group-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &api.User{
+		UUID:     "2",
+		Username: "bot",
+		Type:     api.UserTypeShared,
+		RoleName: api.RoleSharedObserver,
+		Roles:    []*api.Role{{Name: api.RoleSharedObserver}},
+		Scope:    []*api.BaseDTO{{UUID: "group-1"}},
+	}, user)
+	user, err = userClient.AssignRole("2", api.RoleAutomator, "group-1")
+	assert.NoError(t, err)
+	assert.Equal(t, api.UserTypeDedicated, user.Type)
+	assert.Equal(t, []*api.BaseDTO{{UUID: "group-1"}}, user.Scope)
+	user, err = userClient.AssignRole("2", api.RoleAdministrator)
+	assert.NoError(t, err)
+	assert.Equal(t, api.UserTypeDedicated, user.Type)
+	assert.Nil(t, user.Scope)
+	_, err = userClient.AssignRole("2", api.RoleSharedAdvisor)
+	assert.EqualError(t, err, "scope of shared role SHARED_ADVISOR is required")
+
+	assert.NoError(t, userClient.DeleteUser("2"))
+	_, err = userClient.AssignRole("2", api.RoleAdministrator)
+	assert.Error(t, err)
+	list, err := userClient.ListUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+}
+
+func TestUserClient_ADGroups(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /users/ad/groups":
+			fmt.Fprint(w, `[{"uuid":"10","displayName":"turbo-admins","type":"DedicatedCustomer","roleName":"ADMINISTRATOR"}]`)
+		case "POST /users/ad/groups", "PUT /users/ad/groups":
+			var group api.ADGroup
+			json.NewDecoder(r.Body).Decode(&group)
+			group.UUID = "11"
+			json.NewEncoder(w).Encode(&group)
+		case "DELETE /users/ad/groups/11":
+			fmt.Fprint(w, "true")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "users")
+	defer server.Close()
+	userClient := NewUserClient(apiClient)
+
+	groups, err := userClient.ListADGroups()
+	assert.NoError(t, err)
+	assert.Equal(t, []api.ADGroup{{UUID: "10", DisplayName: "turbo-admins", Type: api.UserTypeDedicated,
+		RoleName: api.RoleAdministrator}}, groups)
+
+	_, err = userClient.CreateADGroup("turbo-observers", "")
+	assert.Error(t, err)
+	group, err := userClient.CreateADGroup("turbo-observers", api.RoleSharedObserver, "group-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &api.ADGroup{UUID: "11", DisplayName: "turbo-observers", Type: api.UserTypeShared,
+		RoleName: api.RoleSharedObserver, Scope: []*api.BaseDTO{{UUID: "group-1"}}}, group)
+
+	_, err = userClient.CreateADGroup("turbo-observers", api.RoleSharedObserver)
+	assert.EqualError(t, err, "scope of shared role SHARED_OBSERVER is required")
+	group, err = userClient.CreateADGroup("turbo-automators", api.RoleAutomator, "group-1")
+	assert.NoError(t, err)
+	assert.Equal(t, api.UserTypeDedicated, group.Type)
+
+	group.RoleName = api.RoleSharedAdvisor
+	group, err = userClient.UpdateADGroup(group)
+	assert.NoError(t, err)
+	assert.Equal(t, api.RoleSharedAdvisor, group.RoleName)
+
+	assert.NoError(t, userClient.DeleteADGroup("11"))
+	assert.Error(t, userClient.DeleteADGroup("12"))
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+var (
+	// versionInfoPattern matches the product, version and build in the version info of a server,
+	// i.e. Turbonomic Operations Manager 8.4.2 (Build "20211021092220000") "2021-10-22 12:39:53"
+	versionInfoPattern = regexp.MustCompile(`^\s*(.*?)\s*(\d+\.\d+(?:\.\d+)?)(?:-\S*)?\s*(?:\(Build\s+"?([^")]*)"?\))?`)
+	// The oldest version of XL servers shipping Hydra service
+	minHydraVersion = [3]int{8, 7, 0}
+	// The oldest major version of XL servers, used when the server does not report its market version
+	minXLMajorVersion = 7
+)
+
+// ServerVersion is the version of a Turbonomic server
+type ServerVersion struct {
+	// Product name, i.e. Turbonomic Operations Manager
+	Product string
+	// Version, i.e. 8.4.2, split into its major, minor and patch numbers
+	Version string
+	Major   int
+	Minor   int
+	Patch   int
+	Build   string
+	// Market version, see api.MarketVersionClassic and api.MarketVersionXL; 0 if not reported
+	MarketVersion int
+}
+
+// ParseServerVersion parses the product version reported by a server
+func ParseServerVersion(productVersion *api.ProductVersion) (*ServerVersion, error) {
+	version := &ServerVersion{
+		Version:       productVersion.Version,
+		Build:         productVersion.Build,
+		MarketVersion: productVersion.MarketVersion,
+	}
+	// The first line of the version info describes the product, the following ones the components
+	firstLine := strings.SplitN(productVersion.VersionInfo, "\n", 2)[0]
+	if match := versionInfoPattern.FindStringSubmatch(firstLine); match != nil {
+		version.Product = match[1]
+		if version.Version == "" {
+			version.Version = match[2]
+		}
+		if version.Build == "" {
+			version.Build = match[3]
+		}
+	}
+	if version.Version == "" {
+		return nil, fmt.Errorf("failed to parse server version from %q", productVersion.VersionInfo)
+	}
+	numbers := strings.SplitN(strings.SplitN(version.Version, "-", 2)[0], ".", 3)
+	for i, field := range []*int{&version.Major, &version.Minor, &version.Patch} {
+		if i >= len(numbers) {
+			break
+		}
+		number, err := strconv.Atoi(numbers[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse server version %q: %v", version.Version, err)
+		}
+		*field = number
+	}
+	return version, nil
+}
+
+// String returns the product, version and build of the server
+func (v *ServerVersion) String() string {
+	s := strings.TrimSpace(v.Product + " " + v.Version)
+	if v.Build != "" {
+		s += " (Build " + v.Build + ")"
+	}
+	return s
+}
+
+// AtLeast returns true if the version of the server is the given version or a later one
+func (v *ServerVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// IsXL returns true if the server is an XL server, which is split into services like topology processor
+func (v *ServerVersion) IsXL() bool {
+	if v.MarketVersion != 0 {
+		return v.MarketVersion >= api.MarketVersionXL
+	}
+	return v.Major >= minXLMajorVersion
+}
+
+// SupportsHydra returns true if the server issues Hydra access tokens to its clients
+func (v *ServerVersion) SupportsHydra() bool {
+	return v.IsXL() && v.AtLeast(minHydraVersion[0], minHydraVersion[1], minHydraVersion[2])
+}
+
+// SupportsTopologyProcessorTargets returns true if targets can be added via topology processor service
+func (v *ServerVersion) SupportsTopologyProcessorTargets() bool {
+	return v.IsXL()
+}
+
+// GetServerVersion gets the version of the server
+func (c *APIClient) GetServerVersion() (*ServerVersion, error) {
+	var productVersion api.ProductVersion
+	if err := c.doJSON("get server version", func() *Request {
+		return c.Get().Resource(api.Resource_Type_Admin).Name("versions")
+	}, nil, &productVersion); err != nil {
+		return nil, err
+	}
+	version, err := ParseServerVersion(&productVersion)
+	if err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully got server version %v.", version)
+	return version, nil
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+	"github.com/turbonomic/turbo-api/pkg/client/fake"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	table := []struct {
+		productVersion *api.ProductVersion
+
+		expectedVersion *ServerVersion
+		expectedString  string
+		expectsError    bool
+	}{
+		{
+			// Xl version info
+			productVersion: &api.ProductVersion{
+				VersionInfo: "Turbonomic Operations Manager 8.4.2 (Build \"20211021092220000\") \"2021-10-22 12:39:53\"\n\n" +
+					"api: 8.4.2-SNAPSHOT\n",
+				MarketVersion: 2,
+			},
+
+			expectedVersion: &ServerVersion{Product: "Turbonomic Operations Manager", Version: "8.4.2", Major: 8, Minor: 4, Patch: 2,
+				Build: "20211021092220000", MarketVersion: 2},
+			expectedString: "Turbonomic Operations Manager 8.4.2 (Build 20211021092220000)",
+		},
+		{
+			// Classic version info
+			productVersion: &api.ProductVersion{VersionInfo: "Turbonomic Operations Manager 6.4.10 (Build 20200615)", MarketVersion: 1},
+
+			expectedVersion: &ServerVersion{Product: "Turbonomic Operations Manager", Version: "6.4.10", Major: 6, Minor: 4, Patch: 10,
+				Build: "20200615", MarketVersion: 1},
+		},
+		{
+			// Version and build fields
+			productVersion: &api.ProductVersion{VersionInfo: "Turbonomic 8.7.1-SNAPSHOT", Version: "8.10", Build: "42"},
+
+			expectedVersion: &ServerVersion{Product: "Turbonomic", Version: "8.10", Major: 8, Minor: 10, Build: "42"},
+			expectedString:  "Turbonomic 8.10 (Build 42)",
+		},
+		{
+			// Unknown version
+			productVersion: &api.ProductVersion{VersionInfo: "Turbonomic Operations Manager"},
+
+			expectsError: true,
+		},
+		{
+			// Invalid version
+			productVersion: &api.ProductVersion{Version: "8.x"},
+
+			expectsError: true,
+		},
+	}
+	for _, item := range table {
+		version, err := ParseServerVersion(item.productVersion)
+		if item.expectsError {
+			assert.Error(t, err, item.productVersion)
+			continue
+		}
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, item.expectedVersion, version)
+		if item.expectedString != "" {
+			assert.Equal(t, item.expectedString, version.String())
+		}
+	}
+}
+
+func TestServerVersion_Capabilities(t *testing.T) {
+	table := []struct {
+		version ServerVersion
+
+		expectsXL                   bool
+		expectsHydra                bool
+		expectsTopologyProcessorTgt bool
+	}{
+		{version: ServerVersion{Major: 6, Minor: 4, Patch: 10, MarketVersion: 1}},
+		{version: ServerVersion{Major: 6, Minor: 4}},
+		{version: ServerVersion{Major: 8, Minor: 4, Patch: 2, MarketVersion: 2}, expectsXL: true, expectsTopologyProcessorTgt: true},
+		{version: ServerVersion{Major: 7, Minor: 22}, expectsXL: true, expectsTopologyProcessorTgt: true},
+		{version: ServerVersion{Major: 8, Minor: 7, MarketVersion: 2}, expectsXL: true, expectsHydra: true, expectsTopologyProcessorTgt: true},
+		{version: ServerVersion{Major: 8, Minor: 14, Patch: 3}, expectsXL: true, expectsHydra: true, expectsTopologyProcessorTgt: true},
+		{version: ServerVersion{Major: 8, Minor: 14, Patch: 3, MarketVersion: 1}},
+	}
+	for _, item := range table {
+		version := fmt.Sprintf("%d.%d.%d market %d", item.version.Major, item.version.Minor, item.version.Patch,
+			item.version.MarketVersion)
+		assert.Equal(t, item.expectsXL, item.version.IsXL(), version)
+		assert.Equal(t, item.expectsHydra, item.version.SupportsHydra(), version)
+		assert.Equal(t, item.expectsTopologyProcessorTgt, item.version.SupportsTopologyProcessorTargets(), version)
+	}
+	version := ServerVersion{Major: 8, Minor: 4, Patch: 2}
+	assert.True(t, version.AtLeast(8, 4, 2))
+	assert.True(t, version.AtLeast(7, 22, 9))
+	assert.True(t, version.AtLeast(8, 3, 10))
+	assert.False(t, version.AtLeast(8, 4, 3))
+	assert.False(t, version.AtLeast(9, 0, 0))
+}
+
+func TestAPIClient_GetServerVersion(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.Path != "GET /admin/versions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"versionInfo":"Turbonomic Operations Manager 8.4.2 (Build \"20211021092220000\")","marketVersion":2}`)
+	}, "admin")
+	defer server.Close()
+	version, err := apiClient.GetServerVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "8.4.2", version.Version)
+	assert.True(t, version.SupportsTopologyProcessorTargets())
+}
+
+func TestTurboClient_TargetService(t *testing.T) {
+	server := fake.NewServer("foo", "bar")
+	defer server.Close()
+	turboClient := newTestTurboClient(t, server)
+
+	service, err := turboClient.TargetService()
+	assert.NoError(t, err)
+	assert.Equal(t, TopologyProcessor, service)
+	version, err := turboClient.ServerVersion()
+	assert.NoError(t, err)
+	assert.True(t, version.SupportsHydra())
+	// The version is retrieved once
+	assert.Equal(t, 1, server.RequestCount(http.MethodGet, "/vmturbo/rest/admin/versions"))
+
+	server.SetProductVersion(&api.ProductVersion{VersionInfo: "Turbonomic Operations Manager 6.4.10", MarketVersion: 1})
+	turboClient = newTestTurboClient(t, server)
+	service, err = turboClient.TargetService()
+	assert.NoError(t, err)
+	assert.Equal(t, API, service)
+
+	server.InjectFault(http.MethodGet, "/vmturbo/rest/admin/versions", fake.Fault{StatusCode: http.StatusBadGateway})
+	_, err = newTestTurboClient(t, server).TargetService()
+	assert.EqualError(t, err, "unsuccessful get server version response: 502 Bad Gateway.")
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+// WorkflowClient manages the orchestration workflows run when executing actions via api service.
+// The action scripts are workflows run by the action script probe of the server, which calls them back;
+// a Go program registered as an action script serves the callbacks with RunActionScript.
+type WorkflowClient struct {
+	api *APIClient
+}
+
+// NewWorkflowClient builds a client managing workflows with the session of the given api service client
+func NewWorkflowClient(apiClient *APIClient) *WorkflowClient {
+	return &WorkflowClient{api: apiClient}
+}
+
+// ListWorkflows lists the workflows of the given type, i.e. ACTION_SCRIPT, or all the workflows if empty
+func (c *WorkflowClient) ListWorkflows(workflowType string) ([]api.Workflow, error) {
+	var workflows []api.Workflow
+	if err := c.api.doJSON("list workflows", func() *Request {
+		request := c.api.Get().Resource(api.Resource_Type_Workflows)
+		if workflowType != "" {
+			request.Param("workflow_type", workflowType)
+		}
+		return request
+	}, nil, &workflows); err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// GetWorkflow gets the workflow with the given UUID
+func (c *WorkflowClient) GetWorkflow(uuid string) (*api.Workflow, error) {
+	var workflow api.Workflow
+	if err := c.api.doJSON("get workflow", func() *Request {
+		return c.api.Get().Resource(api.Resource_Type_Workflows).Name(uuid)
+	}, nil, &workflow); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// CreateWorkflow creates a workflow and returns it with its UUID.
+// Only webhook workflows can be created; the other types are discovered from orchestrator targets.
+func (c *WorkflowClient) CreateWorkflow(workflow *api.Workflow) (*api.Workflow, error) {
+	if workflow.Type != api.WorkflowTypeWebhook || workflow.TypeSpecificDetails == nil {
+		return nil, fmt.Errorf("only webhook workflows with details can be created")
+	}
+	var created api.Workflow
+	if err := c.api.doJSON("create workflow", func() *Request {
+		return c.api.Post().Resource(api.Resource_Type_Workflows)
+	}, workflow, &created); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully created workflow %v with UUID %v.", created.DisplayName, created.UUID)
+	return &created, nil
+}
+
+// UpdateWorkflow updates the workflow with the given UUID
+func (c *WorkflowClient) UpdateWorkflow(uuid string, workflow *api.Workflow) (*api.Workflow, error) {
+	var updated api.Workflow
+	if err := c.api.doJSON("update workflow", func() *Request {
+		return c.api.Put().Resource(api.Resource_Type_Workflows).Name(uuid)
+	}, workflow, &updated); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Successfully updated workflow %v.", uuid)
+	return &updated, nil
+}
+
+// DeleteWorkflow deletes the workflow with the given UUID
+func (c *WorkflowClient) DeleteWorkflow(uuid string) error {
+	if err := c.api.doJSON("delete workflow", func() *Request {
+		return c.api.Delete().Resource(api.Resource_Type_Workflows).Name(uuid)
+	}, nil, nil); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Successfully deleted workflow %v.", uuid)
+	return nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/turbonomic/turbo-api/pkg/api"
+)
+
+func TestWorkflowClient(t *testing.T) {
+	apiClient, server := newTestAPIClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /workflows":
+			if r.URL.Query().Get("workflow_type") == api.WorkflowTypeActionScript {
+				fmt.Fprint(w, `[{"uuid":"1","displayName":"pre-move","type":"ACTION_SCRIPT"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"uuid":"1","displayName":"pre-move","type":"ACTION_SCRIPT"},`+
+				`{"uuid":"2","displayName":"notify","type":"WEBHOOK"}]`)
+		case "GET /workflows/1":
+			fmt.Fprint(w, `{"uuid":"1","displayName":"pre-move","type":"ACTION_SCRIPT",`+
+				`"discoveredBy":{"uuid":"target-1"},"parameters":[{"name":"VMT_TARGET_NAME","type":"String"}]}`)
+		case "POST /workflows", "PUT /workflows/3":
+			var workflow api.Workflow
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&workflow))
+			workflow.UUID = "3"
+			json.NewEncoder(w).Encode(&workflow)
+		case "DELETE /workflows/3":
+			fmt.Fprint(w, "true")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, "workflows")
+	defer server.Close()
+	workflowClient := NewWorkflowClient(apiClient)
+
+	workflows, err := workflowClient.ListWorkflows("")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(workflows))
+	workflows, err = workflowClient.ListWorkflows(api.WorkflowTypeActionScript)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Workflow{{UUID: "1", DisplayName: "pre-move", Type: api.WorkflowTypeActionScript}}, workflows)
+
+	workflow, err := workflowClient.GetWorkflow("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "target-1", workflow.DiscoveredBy.UUID)
+	assert.Equal(t, "VMT_TARGET_NAME", workflow.Parameters[0].Name)
+
+	_, err = workflowClient.CreateWorkflow(&api.Workflow{DisplayName: "script", Type: api.WorkflowTypeActionScript})
+	assert.Error(t, err)
+	webhook := &api.Workflow{
+		DisplayName: "notify",
+		Type:        api.WorkflowTypeWebhook,
+		TypeSpecificDetails: &api.WebhookDetails{Type: "WebhookApiDTO", URL: "https://hooks.example.com/turbo",
+			Method: "POST", Template: `{"action":"$action.details"}`},
+	}
+	created, err := workflowClient.CreateWorkflow(webhook)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", created.UUID)
+	assert.Equal(t, webhook.TypeSpecificDetails, created.TypeSpecificDetails)
+
+	created.Description = "Notify the on-call channel"
+	updated, err := workflowClient.UpdateWorkflow(created.UUID, created)
+	assert.NoError(t, err)
+	assert.Equal(t, "Notify the on-call channel", updated.Description)
+
+	assert.NoError(t, workflowClient.DeleteWorkflow("3"))
+	assert.Error(t, workflowClient.DeleteWorkflow("4"))
+}